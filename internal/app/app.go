@@ -5,24 +5,33 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/ZeljkoBenovic/govein/pkg/config"
-	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/ZeljkoBenovic/govein/pkg/dryrun"
+	"github.com/ZeljkoBenovic/govein/pkg/sink"
 	"github.com/ZeljkoBenovic/govein/pkg/veeam"
 )
 
 type App struct {
-	influx         *influx.Influx
+	// mu guards the clients and the config, which are swapped on reload
+	mu             sync.RWMutex
+	reloadMu       sync.Mutex
+	sinks          []sink.Sink
 	veeam          *veeam.Veeam
 	conf           config.Config
 	ctx            context.Context
 	log            *slog.Logger
+	level          *slog.LevelVar
+	ticker         *time.Ticker
+	stopVault      context.CancelFunc
 	healthCheckErr chan error
 }
 
@@ -37,11 +46,16 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("could not create config: %v", err)
 	}
 
-	var logLevel slog.Level
-	if err = logLevel.UnmarshalText([]byte(conf.LogLevel)); err != nil {
-		return nil, fmt.Errorf("could not parse log level: %v", err)
+	// the dry-run points are printed to stdout, the logs must not get in between them
+	logOut := os.Stdout
+	if conf.DryRun != "" {
+		logOut = os.Stderr
+	}
+
+	log, level, err := newLogger(conf.LogLevel, logOut)
+	if err != nil {
+		return nil, err
 	}
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
 
 	v, err := veeam.NewVeeam(ctx, conf, log)
 	if err != nil {
@@ -52,99 +66,164 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("could not connect to veeam server: %v", err)
 	}
 
-	i, err := influx.NewInflux(ctx, conf, log)
-	if err != nil {
-		return nil, fmt.Errorf("could not create influx client: %v", err)
+	var sinks []sink.Sink
+	if conf.DryRun != "" {
+		d, err := dryrun.New(ctx, conf, log, os.Stdout)
+		if err != nil {
+			return nil, fmt.Errorf("could not create dry-run sink: %v", err)
+		}
+
+		sinks = []sink.Sink{d}
+	} else if sinks, err = sink.New(ctx, conf, log); err != nil {
+		return nil, err
 	}
 
 	return &App{
 		ctx:            ctx,
 		log:            log,
+		level:          level,
 		conf:           conf,
 		veeam:          v,
-		influx:         i,
+		sinks:          sinks,
 		healthCheckErr: make(chan error),
 	}, nil
 }
 
+// secretRotated logs into veeam again when its credentials are rotated in vault
+func (a *App) secretRotated(field, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch field {
+	case "veeam.username":
+		a.conf.Veeam.Username = value
+	case "veeam.password":
+		a.conf.Veeam.Password = value
+	default:
+		a.log.Warn("Secret rotated in vault, restart to apply it", "field", field)
+		return
+	}
+
+	if err := a.veeam.SetCredentials(a.conf.Veeam.Username, a.conf.Veeam.Password); err != nil {
+		a.log.Error("Could not apply rotated veeam credentials", "err", err)
+	}
+}
+
+// newLogger returns the logger along with its level, which can be changed on reload
+func newLogger(level string, out io.Writer) (*slog.Logger, *slog.LevelVar, error) {
+	logLevel := new(slog.LevelVar)
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, nil, fmt.Errorf("could not parse log level: %v", err)
+	}
+
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: logLevel})), logLevel, nil
+}
+
 func (a *App) Run() error {
+	if a.conf.DryRun != "" {
+		return a.dryRun()
+	}
+
 	a.log.Info("Veeam metrics collector started")
 
-	tick := time.Tick(time.Duration(a.conf.IntervalSeconds) * time.Second)
-	sig := make(chan os.Signal)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	a.ticker = time.NewTicker(time.Duration(a.conf.IntervalSeconds) * time.Second)
+	a.watchVault(a.conf)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		<-sig
-		a.log.Info("Shutdown signal received")
-		a.influx.FlushAndClose()
-		os.Exit(0)
+		for s := range sig {
+			if s == syscall.SIGHUP {
+				// the reload waits for the running collection, so the signal loop must not block on it
+				a.log.Info("Reload signal received")
+				go a.Reload()
+				continue
+			}
+
+			a.log.Info("Shutdown signal received")
+			_, sinks := a.clients()
+			sink.Close(sinks)
+			os.Exit(0)
+		}
 	}()
 
+	if err := config.Watch(a.ctx, a.conf.Path(), a.log, func() {
+		a.log.Info("Config file changed")
+		a.Reload()
+	}); err != nil {
+		a.log.Warn("Config file changes will not be reloaded", "err", err)
+	}
+
 	go a.runHealthcheckHTTPEndpoint()
 
 	a.log.Info("Gathering data on time interval", "seconds", a.conf.IntervalSeconds)
 
 	for {
-		if err := a.veeam.GetSessions(); err != nil {
-			return err
+		// a failing veeam server or sink, an expired login or a timeout only fail this cycle, the next one may succeed.
+		// Config errors are reported when loading the config, before the collector is started.
+		if err := a.collect(); err != nil {
+			a.log.Error("Veeam metrics collection failed, retrying on the next interval", "error", err)
+		} else {
+			a.log.Info("Veeam metrics collection successfully completed")
 		}
 
-		if err := a.veeam.GetManagedServers(); err != nil {
+		select {
+		case <-a.ctx.Done():
+			return nil
+		case <-a.ticker.C:
+			continue
+		case err := <-a.healthCheckErr:
 			return err
 		}
+	}
+}
 
-		if err := a.veeam.GetRepositories(); err != nil {
-			return err
-		}
+// collect runs a single collection cycle, the config can not be reloaded while it runs
+func (a *App) collect() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
-		if err := a.veeam.GetProxies(); err != nil {
-			return err
-		}
+	if err := a.veeam.Collect(); err != nil {
+		// the collector states and api client stats are stored anyway, they show why the cycle failed
+		return errors.Join(err, sink.StoreStatus(a.sinks, *a.veeam))
+	}
 
-		if err := a.veeam.GetBackupObjects(); err != nil {
-			return err
-		}
+	a.log.Info("Storing data...")
 
-		a.log.Info("Storing data...")
-		if err := a.influx.SetVeeamServerInfo(a.veeam.ServerInfo); err != nil {
-			return err
+	var errs []error
+	for _, s := range a.sinks {
+		if err := s.Store(*a.veeam); err != nil {
+			errs = append(errs, fmt.Errorf("could not store data in %s: %v", s.Name(), err))
 		}
+	}
 
-		if err := a.influx.SetVeeamSessions(a.veeam.Sessions); err != nil {
-			return err
-		}
+	return errors.Join(errs...)
+}
 
-		if err := a.influx.SetManagedServers(a.veeam.ManagedSevers); err != nil {
-			return err
-		}
+// dryRun runs a single collection cycle printing the points, then prints how many were printed per measurement
+func (a *App) dryRun() error {
+	a.log.Info("Dry run started, nothing is stored", "format", a.conf.DryRun)
 
-		if err := a.influx.SetRepositories(*a.veeam); err != nil {
-			return err
-		}
+	if err := a.collect(); err != nil {
+		return err
+	}
 
-		if err := a.influx.SetProxies(a.veeam.Proxies); err != nil {
-			return err
+	for _, s := range a.sinks {
+		if d, ok := s.(*dryrun.DryRun); ok {
+			return d.Summary()
 		}
+	}
 
-		if err := a.influx.SetBackupObjects(a.veeam.BackupObjects); err != nil {
-			return err
-		}
+	return nil
+}
 
-		if err := a.influx.FlushAndClose(); err != nil {
-			return err
-		}
+// clients returns the current veeam client and sinks
+func (a *App) clients() (*veeam.Veeam, []sink.Sink) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
-		a.log.Info("Veeam metrics collection successfully completed")
-		select {
-		case <-a.ctx.Done():
-			return nil
-		case <-tick:
-			continue
-		case err := <-a.healthCheckErr:
-			return err
-		}
-	}
+	return a.veeam, a.sinks
 }
 
 func (a *App) runHealthcheckHTTPEndpoint() {
@@ -152,16 +231,19 @@ func (a *App) runHealthcheckHTTPEndpoint() {
 		start := time.Now()
 		a.log.Info("Running health check probe")
 
-		if err := a.influx.Ping(); err != nil {
-			resp := map[string]string{"status": "error", "component": "influxdb", "error": err.Error()}
-			w.WriteHeader(500)
-			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(resp)
-			a.healthCheckErr <- err
-			return
+		v, sinks := a.clients()
+		for _, s := range sinks {
+			if err := s.Ping(); err != nil {
+				resp := map[string]string{"status": "error", "component": s.Name(), "error": err.Error()}
+				w.WriteHeader(500)
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(resp)
+				a.healthCheckErr <- err
+				return
+			}
 		}
 
-		if err := a.veeam.Ping(); err != nil {
+		if err := v.Ping(); err != nil {
 			resp := map[string]string{"status": "error", "component": "veeam", "error": err.Error()}
 			w.WriteHeader(500)
 			w.Header().Set("Content-Type", "application/json")
@@ -178,6 +260,21 @@ func (a *App) runHealthcheckHTTPEndpoint() {
 		a.log.Info("Health check endpoint done", "done_at", end.Format(time.RFC3339), "duration", fmt.Sprintf("%dms", end.Sub(start).Milliseconds()))
 	})
 
+	// the history endpoints are served by the sqlite sink, when configured
+	http.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		_, sinks := a.clients()
+
+		h, ok := sink.Handler(sinks)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "no history store is configured, add sqlite to sinks"})
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+
 	a.log.Info("Health check endpoint started", "port", a.conf.HealthCheckPort)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", a.conf.HealthCheckPort), nil); err != nil {
 		a.log.Error("Failed to start healthcheck endpoint", "error", err)
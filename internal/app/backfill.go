@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/backfill"
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// Backfill writes the sessions and task sessions of a past time range into influxdb with their original timestamps
+func Backfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	confFile := fs.String("config", "config.yaml", "Path to config file")
+	from := fs.String("from", "", "Start of the range, RFC 3339 time or date, e.g. 2026-01-01")
+	to := fs.String("to", "", "End of the range, RFC 3339 time or date, now or the end of the resumed range by default")
+	window := fs.Duration("window", backfill.DefaultWindow, "Size of the windows the range is paged through in")
+	pageSize := fs.Int("page-size", backfill.DefaultPageSize, "Number of sessions requested per page")
+	checkpoint := fs.String("checkpoint", "govein-backfill.json", "Path to the checkpoint file an interrupted backfill resumes from")
+	var overrides config.OverrideFlags
+	fs.Var(&overrides, "set", "Override a config key, e.g. -set veeam.host=https://vbr:9419 (repeatable)")
+	_ = fs.Parse(args)
+
+	start, err := parseTime(*from)
+	if err != nil || *from == "" {
+		return fmt.Errorf("from must be an RFC 3339 time or a date, got %q", *from)
+	}
+
+	var end time.Time
+	if *to != "" {
+		if end, err = parseTime(*to); err != nil {
+			return fmt.Errorf("to must be an RFC 3339 time or a date, got %q", *to)
+		}
+	}
+
+	conf, err := config.LoadFile(*confFile, overrides...)
+	if err != nil {
+		return fmt.Errorf("could not create config: %v", err)
+	}
+
+	log, _, err := newLogger(conf.LogLevel, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	v, err := veeam.NewVeeam(ctx, conf, log)
+	if err != nil {
+		return fmt.Errorf("could not create veeam client: %v", err)
+	}
+
+	i, err := influx.NewInflux(ctx, conf, log)
+	if err != nil {
+		return fmt.Errorf("could not create influx client: %v", err)
+	}
+	defer i.Close()
+
+	cp, err := backfill.Run(v, i, backfill.Options{
+		From:       start,
+		To:         end,
+		Window:     *window,
+		PageSize:   *pageSize,
+		Checkpoint: *checkpoint,
+	}, log)
+	if err != nil {
+		return fmt.Errorf("backfill interrupted, run it again to resume from %s: %v", *checkpoint, err)
+	}
+
+	log.Info("Backfill done", "from", cp.From.Format(time.RFC3339), "to", cp.To.Format(time.RFC3339),
+		"sessions", cp.Sessions, "task_sessions", cp.TaskSessions)
+
+	return nil
+}
+
+// parseTime parses an RFC 3339 time or a date, which is midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
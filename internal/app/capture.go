@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// Capture runs every collector against the veeam server and saves the raw responses into a fixture bundle,
+// which can be served back with the veeam.replay_file config option
+func Capture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+	confFile := fs.String("config", "config.yaml", "Path to config file")
+	out := fs.String("out", "govein-capture.json.gz", "Path to the fixture bundle")
+	keepIdentifiers := fs.Bool("no-anonymize", false, "Keep hostnames, names and ids in the bundle as they are")
+	var overrides config.OverrideFlags
+	fs.Var(&overrides, "set", "Override a config key, e.g. -set veeam.host=https://vbr:9419 (repeatable)")
+	_ = fs.Parse(args)
+
+	conf, err := config.LoadFile(*confFile, overrides...)
+	if err != nil {
+		return fmt.Errorf("could not create config: %v", err)
+	}
+
+	log, _, err := newLogger(conf.LogLevel, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	rec := veeam.NewRecorder()
+	v, err := veeam.NewVeeam(context.Background(), conf, log, veeam.WithRecorder(rec))
+	if err != nil {
+		return fmt.Errorf("could not create veeam client: %v", err)
+	}
+
+	if err = v.Ping(); err != nil {
+		return fmt.Errorf("could not connect to veeam server: %v", err)
+	}
+
+	if err = v.Collect(); err != nil {
+		return fmt.Errorf("could not collect veeam data: %v", err)
+	}
+
+	b := rec.Bundle(conf.Veeam.XApiVersion)
+	if !*keepIdentifiers {
+		if err = b.Anonymize(); err != nil {
+			return fmt.Errorf("could not anonymize bundle: %v", err)
+		}
+	}
+
+	if err = b.Save(*out); err != nil {
+		return err
+	}
+
+	log.Info("Fixture bundle saved", "file", *out, "responses", len(b.Responses), "anonymized", b.Anonymized)
+
+	return nil
+}
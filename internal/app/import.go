@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/file"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+)
+
+// Import replays line protocol files written by the file sink into the influxdb of the config
+func Import(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	confFile := fs.String("config", "config.yaml", "Path to config file")
+	var overrides config.OverrideFlags
+	fs.Var(&overrides, "set", "Override a config key, e.g. -set influx.host=http://influxdb:8086 (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: govein import [-config config.yaml] [-set key=value] file.lp[.gz]...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files to import")
+	}
+
+	// only the influx section is used, the file does not need a veeam section or influx in its sinks
+	conf, err := config.LoadInfluxFile(*confFile, overrides...)
+	if err != nil {
+		return fmt.Errorf("could not create config: %v", err)
+	}
+
+	log, _, err := newLogger(conf.LogLevel, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	w, err := influx.NewWriter(conf.Influx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Ping(ctx); err != nil {
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		n, err := file.Import(ctx, w, path)
+		if err != nil {
+			_ = w.Close()
+			return err
+		}
+
+		log.Info("File imported", "file", path, "points", n, "influx", conf.Influx.Host)
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/sink"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// Reload loads the config file again and applies it between collection cycles.
+// The running config is kept if the new one is invalid or its clients can not connect.
+func (a *App) Reload() {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	a.mu.RLock()
+	current := a.conf
+	a.mu.RUnlock()
+
+	conf, err := current.Reload()
+	if err != nil {
+		a.log.Error("Could not reload config, keeping the running one", "err", err)
+		return
+	}
+
+	// new clients connect before the swap, so a failure keeps the running ones
+	var (
+		v     *veeam.Veeam
+		sinks []sink.Sink
+	)
+
+	if veeamChanged(current, conf) {
+		if v, err = veeam.NewVeeam(a.ctx, conf, a.log); err == nil {
+			err = v.Ping()
+		}
+
+		if err != nil {
+			a.log.Error("Could not connect to veeam server with the new config, keeping the running one", "err", err)
+			return
+		}
+	}
+
+	if sink.Changed(current, conf) {
+		if sinks, err = sink.New(a.ctx, conf, a.log); err != nil {
+			a.log.Error("Could not connect to the sinks with the new config, keeping the running one", "err", err)
+			return
+		}
+	}
+
+	if err = a.level.UnmarshalText([]byte(conf.LogLevel)); err != nil {
+		a.log.Error("Could not parse log level, keeping the running config", "err", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if v != nil {
+		a.log.Info("Veeam connection settings changed, using the new client")
+		a.veeam = v
+	} else if err = a.veeam.SetConfig(conf); err != nil {
+		a.log.Error("Could not apply the new config, keeping the running one", "err", err)
+		sink.Close(sinks)
+		return
+	}
+
+	if sinks != nil {
+		a.log.Info("Sink settings changed, using the new sinks")
+		sink.Close(a.sinks)
+		a.sinks = sinks
+	} else {
+		for _, s := range a.sinks {
+			if err = s.SetConfig(conf); err != nil {
+				// the config is validated, so this only happens on a bug
+				a.log.Error("Could not apply the new sink config", "sink", s.Name(), "err", err)
+			}
+		}
+	}
+
+	if conf.IntervalSeconds != current.IntervalSeconds && a.ticker != nil {
+		a.log.Info("Gathering data on new time interval", "seconds", conf.IntervalSeconds)
+		a.ticker.Reset(time.Duration(conf.IntervalSeconds) * time.Second)
+	}
+
+	if conf.HealthCheckPort != current.HealthCheckPort || conf.HealthCheckEndpoint != current.HealthCheckEndpoint {
+		a.log.Warn("Health check settings changed, restart to apply them")
+	}
+
+	a.conf = conf
+	a.watchVault(conf)
+
+	a.log.Info("Config reloaded", "file", conf.Path())
+}
+
+// watchVault watches the vault secrets of the config, replacing the watcher of the previous config
+func (a *App) watchVault(conf config.Config) {
+	if a.stopVault != nil {
+		a.stopVault()
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.stopVault = cancel
+
+	go conf.WatchVault(ctx, a.log, a.secretRotated)
+}
+
+func veeamChanged(old, new config.Config) bool {
+	return old.Veeam.Host != new.Veeam.Host ||
+		old.Veeam.XApiVersion != new.Veeam.XApiVersion ||
+		old.Veeam.TrustSelfSignedCert != new.Veeam.TrustSelfSignedCert ||
+		old.Veeam.Username != new.Veeam.Username ||
+		old.Veeam.Password != new.Veeam.Password ||
+		old.Veeam.ReplayFile != new.Veeam.ReplayFile ||
+		old.Veeam.Concurrency != new.Veeam.Concurrency ||
+		old.Veeam.RequestTimeoutSeconds != new.Veeam.RequestTimeoutSeconds ||
+		old.Veeam.Retry != new.Veeam.Retry ||
+		old.Veeam.CircuitBreaker != new.Veeam.CircuitBreaker
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+)
+
+// Validate reports every problem of the config file, unknown keys included, with its file and line.
+// The exec: secret commands are not run.
+func Validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	confFile := fs.String("config", "config.yaml", "Path to config file")
+	var overrides config.OverrideFlags
+	fs.Var(&overrides, "set", "Override a config key, e.g. -set veeam.host=https://vbr:9419 (repeatable)")
+	_ = fs.Parse(args)
+
+	err := config.CheckFile(*confFile, overrides...)
+
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		for _, p := range verr.Problems {
+			fmt.Println(p)
+		}
+
+		return fmt.Errorf("%s: %d problem(s) found", *confFile, len(verr.Problems))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: config is valid\n", *confFile)
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
 	"github.com/magefile/mage/mg" // mg contains helpful utility functions, like Deps
 )
 
@@ -47,6 +48,30 @@ func Docker() error {
 	return cmd.Run()
 }
 
+// Run the full stack against a fake veeam server, no Veeam B&R needed
+func Demo() error {
+	srv := veeamtest.New()
+	url, err := srv.Listen("127.0.0.1:9419")
+	if err != nil {
+		return fmt.Errorf("could not start fake veeam server: %v", err)
+	}
+	defer srv.Close()
+
+	fmt.Println("Fake veeam server listening on", url)
+
+	cmd := exec.Command("docker", "compose", "-f", "docker/docker-compose.yaml", "up", "-d", "influxdb", "grafana")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return err
+	}
+
+	cmd = exec.Command("go", "run", "main.go", "-config", "examples/config-demo.yaml")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 func ExportConfig() error {
 	cmd := exec.Command("go", "run", "main.go", "-export")
 	cmd.Stdout = os.Stdout
@@ -2,11 +2,37 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/ZeljkoBenovic/govein/internal/app"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "capture":
+			if err := app.Capture(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "backfill":
+			if err := app.Backfill(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "import":
+			if err := app.Import(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "validate":
+			if err := app.Validate(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	a, err := app.New()
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,207 @@
+// Package backfill writes the sessions and task sessions of a past time range with their original timestamps,
+// page by page, checkpointing its progress so an interrupted backfill resumes where it stopped
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+const (
+	DefaultWindow   = 24 * time.Hour
+	DefaultPageSize = 500
+)
+
+// Source pages through the sessions of a time range, it is implemented by *veeam.Veeam
+type Source interface {
+	SessionsPage(from, to time.Time, skip, limit int) (veeam.Sessions, error)
+	TaskSessionsPage(from, to time.Time, skip, limit int) (veeam.TaskSessions, error)
+}
+
+// Target stores the pages, it is implemented by *influx.Influx
+type Target interface {
+	SetVeeamSessions(sess veeam.Sessions) error
+	SetTaskSessions(ts veeam.TaskSessions) error
+}
+
+type Options struct {
+	From time.Time
+	// To is now by default, or the end of the range of the checkpoint being resumed
+	To time.Time
+	// Window splits the range, each window is paged through on its own. 24 hours by default.
+	Window   time.Duration
+	PageSize int
+	// Checkpoint is the file the progress is saved to after every page, no progress is saved if empty
+	Checkpoint string
+}
+
+// Checkpoint is the progress of a backfill, the windows before Window are done
+type Checkpoint struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Window time.Time `json:"window"`
+	// the api items of the window already stored
+	SessionsSkip     int  `json:"sessions_skip"`
+	TaskSessionsSkip int  `json:"task_sessions_skip"`
+	SessionsDone     bool `json:"sessions_done"`
+	// totals of the stored items, filtered out items excluded
+	Sessions     int `json:"sessions"`
+	TaskSessions int `json:"task_sessions"`
+}
+
+// Run backfills the range of the options, resuming from the checkpoint file if it exists.
+// The checkpoint file is removed once the range is done.
+func Run(src Source, dst Target, opts Options, log *slog.Logger) (Checkpoint, error) {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	cp, err := load(opts)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	if cp.To.IsZero() {
+		cp.To = time.Now()
+	}
+	opts.To = cp.To
+
+	if !opts.From.Before(opts.To) {
+		return Checkpoint{}, fmt.Errorf("from must be before to")
+	}
+
+	if cp.Window.After(opts.From) {
+		log.Info("Resuming backfill", "window", cp.Window.Format(time.RFC3339), "sessions", cp.Sessions, "task_sessions", cp.TaskSessions)
+	}
+
+	for cp.Window.Before(opts.To) {
+		end := cp.Window.Add(opts.Window)
+		if end.After(opts.To) {
+			end = opts.To
+		}
+
+		log.Info("Backfilling window", "from", cp.Window.Format(time.RFC3339), "to", end.Format(time.RFC3339))
+
+		for !cp.SessionsDone {
+			page, err := src.SessionsPage(cp.Window, end, cp.SessionsSkip, opts.PageSize)
+			if err != nil {
+				return cp, err
+			}
+
+			if err = dst.SetVeeamSessions(page); err != nil {
+				return cp, err
+			}
+
+			cp.Sessions += len(page.Data)
+			cp.SessionsSkip += int(page.Pagination.Count)
+			cp.SessionsDone = lastPage(page.Pagination, cp.SessionsSkip, opts.PageSize)
+
+			if err = save(opts.Checkpoint, cp); err != nil {
+				return cp, err
+			}
+		}
+
+		for {
+			page, err := src.TaskSessionsPage(cp.Window, end, cp.TaskSessionsSkip, opts.PageSize)
+			if err != nil {
+				return cp, err
+			}
+
+			if err = dst.SetTaskSessions(page); err != nil {
+				return cp, err
+			}
+
+			cp.TaskSessions += len(page.Data)
+			cp.TaskSessionsSkip += int(page.Pagination.Count)
+
+			if lastPage(page.Pagination, cp.TaskSessionsSkip, opts.PageSize) {
+				break
+			}
+
+			if err = save(opts.Checkpoint, cp); err != nil {
+				return cp, err
+			}
+		}
+
+		cp.Window = end
+		cp.SessionsSkip, cp.TaskSessionsSkip, cp.SessionsDone = 0, 0, false
+
+		if err = save(opts.Checkpoint, cp); err != nil {
+			return cp, err
+		}
+	}
+
+	if opts.Checkpoint != "" {
+		if err = os.Remove(opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return cp, fmt.Errorf("could not remove checkpoint: %v", err)
+		}
+	}
+
+	return cp, nil
+}
+
+// lastPage reports whether the page was the last one of the window
+func lastPage(p veeam.Pagination, skip, pageSize int) bool {
+	return p.Count < int64(pageSize) || int64(skip) >= p.Total
+}
+
+// load returns the checkpoint of the range, a new one if the file does not exist
+func load(opts Options) (Checkpoint, error) {
+	cp := Checkpoint{From: opts.From, To: opts.To, Window: opts.From}
+	if opts.Checkpoint == "" {
+		return cp, nil
+	}
+
+	b, err := os.ReadFile(opts.Checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("could not read checkpoint: %v", err)
+	}
+
+	var saved Checkpoint
+	if err = json.Unmarshal(b, &saved); err != nil {
+		return Checkpoint{}, fmt.Errorf("could not parse checkpoint %s: %v", opts.Checkpoint, err)
+	}
+
+	if !saved.From.Equal(opts.From) || (!opts.To.IsZero() && !saved.To.Equal(opts.To)) {
+		return Checkpoint{}, fmt.Errorf("checkpoint %s is for the range %s to %s, remove it to backfill another range",
+			opts.Checkpoint, saved.From.Format(time.RFC3339), saved.To.Format(time.RFC3339))
+	}
+
+	return saved, nil
+}
+
+// save writes the checkpoint to a temporary file first, so an interruption never leaves a partial checkpoint
+func save(path string, cp Checkpoint) error {
+	if path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(path+".tmp", b, 0o644); err != nil {
+		return fmt.Errorf("could not save checkpoint: %v", err)
+	}
+
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("could not save checkpoint: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+)
+
+var start = time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+// source serves a session every 2 hours over 3 days, and the task sessions fixture
+func source(t *testing.T) *veeam.Veeam {
+	t.Helper()
+
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	var sessions []map[string]any
+	for n := range 36 {
+		created := start.Add(time.Duration(n) * 2 * time.Hour)
+		sessions = append(sessions, map[string]any{
+			"id":           fmt.Sprintf("b5a0cb8e-2f7e-4a45-9cbb-00000000%04d", n),
+			"name":         "Daily VM Backup",
+			"sessionType":  "BackupJob",
+			"state":        "Stopped",
+			"creationTime": created,
+			"endTime":      created.Add(30 * time.Minute),
+			"result":       map[string]any{"result": "Success"},
+		})
+	}
+
+	if err := srv.SetFixture("/api/v1/sessions", map[string]any{"data": sessions}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := veeam.NewVeeam(context.Background(), srv.Config(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	return v
+}
+
+// target records the stored ids, and fails once after the given number of pages
+type target struct {
+	sessions     map[string]int
+	taskSessions map[string]int
+	failAfter    int
+	pages        int
+}
+
+func newTarget() *target {
+	return &target{sessions: make(map[string]int), taskSessions: make(map[string]int)}
+}
+
+func (t *target) page() error {
+	t.pages++
+	if t.failAfter > 0 && t.pages > t.failAfter {
+		t.failAfter = 0
+		return errors.New("influx is down")
+	}
+
+	return nil
+}
+
+func (t *target) SetVeeamSessions(sess veeam.Sessions) error {
+	if err := t.page(); err != nil {
+		return err
+	}
+
+	for _, s := range sess.Data {
+		t.sessions[s.ID]++
+	}
+
+	return nil
+}
+
+func (t *target) SetTaskSessions(ts veeam.TaskSessions) error {
+	if err := t.page(); err != nil {
+		return err
+	}
+
+	for _, s := range ts.Data {
+		t.taskSessions[s.ID]++
+	}
+
+	return nil
+}
+
+func (t *target) assertOnce(tb testing.TB, sessions, taskSessions int) {
+	tb.Helper()
+
+	if len(t.sessions) != sessions || len(t.taskSessions) != taskSessions {
+		tb.Fatalf("expected %d sessions and %d task sessions, got %d and %d",
+			sessions, taskSessions, len(t.sessions), len(t.taskSessions))
+	}
+
+	for id, n := range t.sessions {
+		if n != 1 {
+			tb.Errorf("session %s stored %d times", id, n)
+		}
+	}
+
+	for id, n := range t.taskSessions {
+		if n != 1 {
+			tb.Errorf("task session %s stored %d times", id, n)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dst := newTarget()
+
+	cp, err := Run(source(t), dst, Options{
+		From:     start,
+		To:       start.Add(4 * 24 * time.Hour),
+		Window:   24 * time.Hour,
+		PageSize: 5,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst.assertOnce(t, 36, 4)
+
+	if cp.Sessions != 36 || cp.TaskSessions != 4 {
+		t.Errorf("unexpected totals %+v", cp)
+	}
+}
+
+func TestResume(t *testing.T) {
+	v := source(t)
+	dst := newTarget()
+	dst.failAfter = 7
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	opts := Options{
+		From:       start,
+		To:         start.Add(4 * 24 * time.Hour),
+		Window:     24 * time.Hour,
+		PageSize:   5,
+		Checkpoint: path,
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if _, err := Run(v, dst, opts, log); err == nil {
+		t.Fatal("expected the backfill to be interrupted")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected a checkpoint: %v", err)
+	}
+
+	// another range is refused
+	other := opts
+	other.From = start.Add(time.Hour)
+	if _, err := Run(v, dst, other, log); err == nil || !strings.Contains(err.Error(), "remove it") {
+		t.Fatalf("expected a range mismatch, got %v", err)
+	}
+
+	// the end of the range defaults to the one of the checkpoint
+	opts.To = time.Time{}
+	if _, err := Run(v, dst, opts, log); err != nil {
+		t.Fatal(err)
+	}
+
+	dst.assertOnce(t, 36, 4)
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the checkpoint to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,480 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validConfig = `veeam:
+  host: https://veeam.lab.local:9419
+  x_api_version: 1.2-rev0
+  username: admin
+  password: secret
+influx:
+  host: http://influxdb:8086
+  token: token
+  org: govein
+  bucket: veeam
+log_level: INFO
+interval_seconds: 600
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	conf, err := LoadFile(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.IntervalSeconds != 600 || conf.Veeam.Username != "admin" {
+		t.Errorf("unexpected config %+v", conf)
+	}
+
+	// defaults are kept for keys missing from the file
+	if conf.HealthCheckPort != 8080 {
+		t.Errorf("expected default health check port, got %d", conf.HealthCheckPort)
+	}
+}
+
+func TestLoadFileProblems(t *testing.T) {
+	content := strings.Replace(validConfig, "  username: admin\n", "  usrname: admin\n", 1)
+	content = strings.Replace(content, "interval_seconds: 600", "interval_seconds: -1", 1)
+	path := writeConfig(t, content)
+
+	_, err := LoadFile(path)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	want := map[string]bool{
+		path + ":4: field usrname not found in type config.Veeam":                                               false,
+		path + ": veeam.username: placeholder value \"<veeam-admin or VEEAM_ADMIN_USERNAME>\" must be replaced": false,
+		path + ":12:19: interval_seconds: must be a positive number of seconds, got -1":                         false,
+	}
+
+	for _, p := range verr.Problems {
+		if _, ok := want[p.String()]; !ok {
+			t.Errorf("unexpected problem %q", p)
+			continue
+		}
+		want[p.String()] = true
+	}
+
+	for p, found := range want {
+		if !found {
+			t.Errorf("missing problem %q", p)
+		}
+	}
+}
+
+func TestLoadFileEnvOverridesPlaceholders(t *testing.T) {
+	content := strings.Replace(validConfig, "  password: secret\n", "  password: <veeam-admin-password or VEEAM_ADMIN_PASSWORD>\n", 1)
+	t.Setenv("VEEAM_ADMIN_PASSWORD", "from-env")
+
+	conf, err := LoadFile(writeConfig(t, content))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.Veeam.Password != "from-env" {
+		t.Errorf("expected password from env, got %q", conf.Veeam.Password)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.Veeam.Username, c.Veeam.Password = "admin", "secret"
+	c.Influx.Token, c.Influx.Org, c.Influx.Bucket = "token", "org", "bucket"
+	c.Veeam.Host = "ftp://veeam:9419"
+	c.HealthCheckPort = 70000
+
+	problems := Validate(c)
+
+	fields := make(map[string]bool)
+	for _, p := range problems {
+		fields[p.Field] = true
+	}
+
+	if len(problems) != 2 || !fields["veeam.host"] || !fields["health_check_port"] {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
+
+func TestValidateInfluxVersions(t *testing.T) {
+	cases := []struct {
+		influx Influx
+		want   []string
+	}{
+		{Influx{Version: 1, Host: "http://influx:8086", Database: "veeam"}, nil},
+		{Influx{Version: 1, Host: "http://influx:8086", Username: "govein"}, []string{"influx.password", "influx.database"}},
+		{Influx{Version: 3, Host: "http://influx:8181", Token: "token"}, []string{"influx.database"}},
+		{Influx{Version: 4, Host: "http://influx:8086"}, []string{"influx.version"}},
+	}
+
+	for _, c := range cases {
+		conf := Default()
+		conf.Veeam.Username, conf.Veeam.Password = "admin", "secret"
+		conf.Influx = c.influx
+
+		var got []string
+		for _, p := range Validate(conf) {
+			got = append(got, p.Field)
+		}
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("version %d: expected problems %v, got %v", c.influx.Version, c.want, got)
+		}
+	}
+}
+
+func TestValidateSinks(t *testing.T) {
+	conf := Default()
+	conf.Veeam.Username, conf.Veeam.Password = "admin", "secret"
+	conf.Sinks = []string{SinkOTLP, "kafka"}
+
+	var got []string
+	for _, p := range Validate(conf) {
+		got = append(got, p.Field)
+	}
+
+	// influx is not validated when it is not used
+	if want := []string{"sinks.1", "otlp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected problems %v, got %v", want, got)
+	}
+
+	conf.Sinks = []string{SinkOTLP}
+	conf.OTLP = &OTLP{Endpoint: "otel-collector:4317", Protocol: "udp"}
+
+	got = nil
+	for _, p := range Validate(conf) {
+		got = append(got, p.Field)
+	}
+
+	if want := []string{"otlp.endpoint", "otlp.protocol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected problems %v, got %v", want, got)
+	}
+
+	conf.Sinks = []string{SinkSQLite}
+	conf.SQLite = &SQLite{RetentionDays: -1}
+
+	got = nil
+	for _, p := range Validate(conf) {
+		got = append(got, p.Field)
+	}
+
+	if want := []string{"sqlite.path", "sqlite.retention_days"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected problems %v, got %v", want, got)
+	}
+
+	conf.Sinks = []string{SinkFile}
+	conf.File = &File{Dir: "/var/lib/govein", Format: "parquet", MaxFiles: -1}
+
+	got = nil
+	for _, p := range Validate(conf) {
+		got = append(got, p.Field)
+	}
+
+	if want := []string{"file.format", "file.max_files"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected problems %v, got %v", want, got)
+	}
+}
+
+func TestLoadInfluxFile(t *testing.T) {
+	// no veeam section and influx is not a sink
+	content := `sinks: [file]
+influx:
+  host: http://influxdb:8086
+  token: token
+  org: govein
+  bucket: veeam
+`
+
+	conf, err := LoadInfluxFile(writeConfig(t, content))
+	if err != nil {
+		t.Fatalf("expected valid influx config, got %v", err)
+	}
+
+	if conf.Influx.Bucket != "veeam" {
+		t.Errorf("unexpected influx config %+v", conf.Influx)
+	}
+
+	_, err = LoadInfluxFile(writeConfig(t, strings.Replace(content, "  bucket: veeam\n", "", 1)))
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || verr.Problems[0].Field != "influx.bucket" {
+		t.Errorf("expected the missing bucket to be reported, got %v", err)
+	}
+}
+
+func TestLoadFileOverrides(t *testing.T) {
+	t.Setenv("VEEAM_ADMIN_USERNAME", "legacy")
+	t.Setenv("GOVEIN_VEEAM_USERNAME", "from-env")
+	t.Setenv("GOVEIN_VEEAM_HOST", "https://env.lab.local:9419")
+	t.Setenv("GOVEIN_VEEAM_TRUST_SELF_SIGNED_CERT", "true")
+	t.Setenv("GOVEIN_VEEAM_EXCLUDED_JOB_TYPES", "Backup, Replica")
+	t.Setenv("GOVEIN_HEALTH_CHECK_PORT", "9090")
+
+	conf, err := LoadFile(writeConfig(t, validConfig),
+		Override{Source: "-set", Field: "veeam.host", Value: "https://flag.lab.local:9419"})
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	// file < env < flags
+	if conf.Veeam.Host != "https://flag.lab.local:9419" {
+		t.Errorf("expected host from flag, got %q", conf.Veeam.Host)
+	}
+
+	if conf.Veeam.Username != "from-env" || !conf.Veeam.TrustSelfSignedCert || conf.HealthCheckPort != 9090 {
+		t.Errorf("unexpected config %+v", conf)
+	}
+
+	if _, ok := conf.Veeam.ExcludedJobTypes["Replica"]; !ok || len(conf.Veeam.ExcludedJobTypes) != 2 {
+		t.Errorf("unexpected excluded job types %v", conf.Veeam.ExcludedJobTypes)
+	}
+}
+
+func TestLoadFileInvalidOverride(t *testing.T) {
+	t.Setenv("GOVEIN_INTERVAL_SECONDS", "hourly")
+
+	_, err := LoadFile(writeConfig(t, validConfig))
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 {
+		t.Fatalf("expected a single problem, got %v", err)
+	}
+
+	want := `interval_seconds: invalid value from GOVEIN_INTERVAL_SECONDS: invalid number "hourly"`
+	if verr.Problems[0].String() != want {
+		t.Errorf("expected %q, got %q", want, verr.Problems[0])
+	}
+}
+
+func TestLoadFileUnknownEnv(t *testing.T) {
+	t.Setenv("GOVEIN_VEEAM_HOTS", "https://env.lab.local:9419")
+
+	_, err := LoadFile(writeConfig(t, validConfig))
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 {
+		t.Fatalf("expected a single problem, got %v", err)
+	}
+
+	want := "GOVEIN_VEEAM_HOTS: env var does not match a config key"
+	if verr.Problems[0].String() != want {
+		t.Errorf("expected %q, got %q", want, verr.Problems[0])
+	}
+}
+
+func TestEnvField(t *testing.T) {
+	type item struct {
+		Name string `yaml:"name"`
+	}
+
+	type nested struct {
+		Items []item `yaml:"items"`
+		Log   string `yaml:"log"`
+		Level string `yaml:"log_level"`
+	}
+
+	cases := map[string]string{
+		"items_0_name": "items.0.name",
+		"items_3":      "items.3",
+		"log":          "log",
+		"log_level":    "log_level",
+		"items_x":      "",
+		"nope":         "",
+	}
+
+	for name, want := range cases {
+		got, ok := envField(reflect.TypeOf(nested{}), name)
+		if got != want || ok != (want != "") {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestLoadFileSecrets(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "veeam-password")
+	if err := os.WriteFile(passwordFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SECRET_INFLUX_TOKEN", "from-env")
+
+	content := strings.Replace(validConfig, "  password: secret\n", "  password_file: "+passwordFile+"\n", 1)
+	content = strings.Replace(content, "  token: token\n", "  token: env:SECRET_INFLUX_TOKEN\n", 1)
+	content = strings.Replace(content, "  username: admin\n", "  username: exec:echo from-exec\n", 1)
+
+	conf, err := LoadFile(writeConfig(t, content))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.Veeam.Password != "from-file" || conf.Influx.Token != "from-env" || conf.Veeam.Username != "from-exec" {
+		t.Errorf("unexpected secrets %q %q %q", conf.Veeam.Password, conf.Influx.Token, conf.Veeam.Username)
+	}
+}
+
+func TestLoadFileSecretPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "veeam-password")
+	tokenFile := filepath.Join(dir, "influx-token")
+
+	// file contents are never resolved as references
+	if err := os.WriteFile(passwordFile, []byte("env:from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("from-env-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	content := strings.Replace(validConfig, "  password: secret\n", "  password_file: "+passwordFile+"\n", 1)
+	content = strings.Replace(content, "  username: admin\n", "  username: literal:exec:admin\n", 1)
+
+	conf, err := LoadFile(writeConfig(t, content))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.Veeam.Password != "env:from-file" || conf.Veeam.Username != "exec:admin" {
+		t.Errorf("unexpected secrets %q %q", conf.Veeam.Password, conf.Veeam.Username)
+	}
+
+	// env vars override the file, whether they set the secret or its file
+	t.Setenv("VEEAM_ADMIN_PASSWORD", "from-env")
+	t.Setenv("GOVEIN_INFLUX_TOKEN_FILE", tokenFile)
+
+	if conf, err = LoadFile(writeConfig(t, content)); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.Veeam.Password != "from-env" || conf.Influx.Token != "from-env-file" {
+		t.Errorf("unexpected secrets %q %q", conf.Veeam.Password, conf.Influx.Token)
+	}
+}
+
+func TestCheckFileExec(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+
+	content := strings.Replace(validConfig, "  username: admin\n", "  username: exec:touch "+marker+"\n", 1)
+	content = strings.Replace(content, "  password: secret\n", "  password: exec:govein-missing-command\n", 1)
+	path := writeConfig(t, content)
+
+	err := CheckFile(path)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || verr.Problems[0].Field != "veeam.password" {
+		t.Fatalf("expected the missing command to be reported, got %v", err)
+	}
+
+	if _, err = os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected the secret command not to run")
+	}
+}
+
+func TestLoadFileSecretProblems(t *testing.T) {
+	content := strings.Replace(validConfig, "  token: token\n", "  token: env:TEST_GOVEIN_MISSING\n", 1)
+	path := writeConfig(t, content)
+
+	_, err := LoadFile(path)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 {
+		t.Fatalf("expected a single problem, got %v", err)
+	}
+
+	want := path + ":8:10: influx.token: secret env var TEST_GOVEIN_MISSING is not set"
+	if verr.Problems[0].String() != want {
+		t.Errorf("expected %q, got %q", want, verr.Problems[0])
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, validConfig)
+
+	conf, err := LoadFile(path, Override{Source: "-set", Field: "log_level", Value: "DEBUG"})
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	if err = Watch(ctx, path, slog.New(slog.NewTextHandler(io.Discard, nil)), func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	content := strings.Replace(validConfig, "interval_seconds: 600", "interval_seconds: 60", 1)
+	if err = os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("config file change was not reported")
+	}
+
+	reloaded, err := conf.Reload()
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	// overrides are applied again on reload
+	if reloaded.IntervalSeconds != 60 || reloaded.LogLevel != "DEBUG" {
+		t.Errorf("unexpected reloaded config %+v", reloaded)
+	}
+}
+
+func TestLoadFileFilters(t *testing.T) {
+	content := validConfig + `filters:
+  include:
+    - field: platform
+      glob: VMware
+  exclude:
+    - field: vm_name
+      glob: "test-*"
+`
+	path := writeConfig(t, content)
+
+	_, err := LoadFile(path)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 {
+		t.Fatalf("expected a single problem, got %v", err)
+	}
+
+	want := path + `:18:7: filters.exclude.0: unknown field "vm_name", must be one of job_name, session_type, platform, repository, proxy, object_path`
+	if verr.Problems[0].String() != want {
+		t.Errorf("expected %q, got %q", want, verr.Problems[0])
+	}
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the env vars overriding config keys,
+// e.g. GOVEIN_VEEAM_HOST overrides veeam.host and GOVEIN_INTERVAL_SECONDS overrides interval_seconds
+const EnvPrefix = "GOVEIN_"
+
+// Override is a single config value set from an env var or a flag.
+// Lists and sets of strings take comma separated values, maps of strings take comma separated key=value pairs.
+type Override struct {
+	// Source is the env var name or the flag the value came from
+	Source string
+	// Field is the dotted yaml path of the config key
+	Field string
+	Value string
+}
+
+// envOverrides maps the GOVEIN_ env vars onto yaml paths.
+// Nested keys are joined with _, list items are addressed by their index, e.g. GOVEIN_TAG_RULES_0_PATTERN.
+// Env vars not matching a config key are reported as problems, as a typo would otherwise go unnoticed.
+func envOverrides(environ []string) ([]Override, []Problem) {
+	var (
+		overrides []Override
+		problems  []Problem
+	)
+
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+
+		field, ok := envField(reflect.TypeOf(Config{}), strings.ToLower(strings.TrimPrefix(name, EnvPrefix)))
+		if !ok {
+			problems = append(problems, Problem{Field: name, Message: "env var does not match a config key"})
+			continue
+		}
+
+		overrides = append(overrides, Override{Source: name, Field: field, Value: value})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Field < problems[j].Field
+	})
+
+	// apply parents before their children, so GOVEIN_X_0 can be refined by GOVEIN_X_0_Y
+	sort.Slice(overrides, func(i, j int) bool {
+		if len(overrides[i].Field) != len(overrides[j].Field) {
+			return len(overrides[i].Field) < len(overrides[j].Field)
+		}
+		return overrides[i].Field < overrides[j].Field
+	})
+
+	return overrides, problems
+}
+
+// envField resolves the underscore separated env var name against the yaml keys of t.
+// yaml keys contain underscores themselves, so the longest matching key wins.
+func envField(t reflect.Type, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return envField(t.Elem(), name)
+	case reflect.Struct:
+		var (
+			best     string
+			bestRest string
+			bestType reflect.Type
+		)
+
+		for i := 0; i < t.NumField(); i++ {
+			key := yamlKey(t.Field(i))
+			if key == "" || key == "-" {
+				continue
+			}
+
+			if name == key {
+				return key, true
+			}
+
+			if strings.HasPrefix(name, key+"_") && len(key) > len(best) {
+				best, bestRest, bestType = key, strings.TrimPrefix(name, key+"_"), t.Field(i).Type
+			}
+		}
+
+		if best == "" {
+			return "", false
+		}
+
+		rest, ok := envField(bestType, bestRest)
+		if !ok {
+			return "", false
+		}
+
+		return best + "." + rest, true
+	case reflect.Slice:
+		idx, rest, _ := strings.Cut(name, "_")
+		if _, err := strconv.Atoi(idx); err != nil {
+			return "", false
+		}
+
+		if rest == "" {
+			return idx, true
+		}
+
+		sub, ok := envField(t.Elem(), rest)
+		if !ok {
+			return "", false
+		}
+
+		return idx + "." + sub, true
+	case reflect.Map:
+		// map keys are case-sensitive and can not be recovered from an env var name
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+func yamlKey(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if key == "" {
+		return strings.ToLower(f.Name)
+	}
+
+	return key
+}
+
+func set(v reflect.Value, path []string, value string) error {
+	if len(path) == 0 {
+		return setValue(v, value)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return set(v.Elem(), path, value)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if yamlKey(v.Type().Field(i)) == path[0] {
+				return set(v.Field(i), path[1:], value)
+			}
+		}
+		return fmt.Errorf("unknown key %q", path[0])
+	case reflect.Slice:
+		idx, err := strconv.Atoi(path[0])
+		if err != nil || idx < 0 {
+			return fmt.Errorf("invalid list index %q", path[0])
+		}
+
+		if idx >= v.Len() {
+			grown := reflect.MakeSlice(v.Type(), idx+1, idx+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+
+		return set(v.Index(idx), path[1:], value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(reflect.ValueOf(path[0])); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		if err := set(elem, path[1:], value); err != nil {
+			return err
+		}
+
+		v.SetMapIndex(reflect.ValueOf(path[0]), elem)
+
+		return nil
+	default:
+		return fmt.Errorf("%q is not a nested key", path[0])
+	}
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), value)
+	case reflect.Slice:
+		items := splitList(value)
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(value) {
+			key, val, _ := strings.Cut(item, "=")
+			elem := reflect.New(v.Type().Elem()).Elem()
+
+			// sets, such as map[string]struct{}, only take keys
+			if elem.Kind() != reflect.Struct {
+				if err := setValue(elem, val); err != nil {
+					return err
+				}
+			}
+
+			m.SetMapIndex(reflect.ValueOf(key), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported value type %s", v.Type())
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// applyOverrides sets the overrides in order, failures are reported as problems of the overridden field
+func applyOverrides(c *Config, overrides []Override) []Problem {
+	var problems []Problem
+
+	for _, o := range overrides {
+		if err := set(reflect.ValueOf(c).Elem(), strings.Split(o.Field, "."), o.Value); err != nil {
+			problems = append(problems, Problem{Field: o.Field, Message: fmt.Sprintf("invalid value from %s: %v", o.Source, err)})
+		}
+	}
+
+	return problems
+}
+
+// OverrideFlags collects the repeatable -set key.path=value flag
+type OverrideFlags []Override
+
+func (f *OverrideFlags) String() string {
+	pairs := make([]string, 0, len(*f))
+	for _, o := range *f {
+		pairs = append(pairs, o.Field+"="+o.Value)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (f *OverrideFlags) Set(s string) error {
+	field, value, ok := strings.Cut(s, "=")
+	if !ok || field == "" {
+		return fmt.Errorf("expected key.path=value, got %q", s)
+	}
+
+	*f = append(*f, Override{Source: "-set " + field, Field: field, Value: value})
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// secret reference schemes, e.g. password: file:/run/secrets/veeam-password
+const (
+	fileScheme = "file:"
+	envScheme  = "env:"
+	execScheme = "exec:"
+	// literalScheme escapes a secret starting with a scheme, e.g. password: literal:env:abc is the password env:abc
+	literalScheme = "literal:"
+)
+
+// execTimeout limits the runtime of exec: secret commands
+const execTimeout = 30 * time.Second
+
+// secretResolver returns the secret a reference, stripped of its scheme, points to
+type secretResolver func(ref string) (string, error)
+
+// secretResolvers returns the resolver of every supported scheme
+func secretResolvers() map[string]secretResolver {
+	return map[string]secretResolver{
+		fileScheme: readSecretFile,
+		envScheme:  readSecretEnv,
+		execScheme: readSecretExec,
+	}
+}
+
+// checkResolvers returns the resolvers used to validate a config, exec: commands are only looked up
+func checkResolvers() map[string]secretResolver {
+	resolvers := secretResolvers()
+	resolvers[execScheme] = lookupSecretExec
+
+	return resolvers
+}
+
+// resolveSecretFiles reads the *_file variants set or changed since prev into their fields.
+// It runs after every layer of the config, so a password set by an env var overrides the password_file
+// of the config file, and a password_file set by an env var overrides the password of the config file.
+func resolveSecretFiles(c *Config, prev Config) []Problem {
+	var problems []Problem
+
+	files := []struct {
+		field string
+		file  string
+		prev  string
+		value *string
+	}{
+		{"veeam.password_file", c.Veeam.PasswordFile, prev.Veeam.PasswordFile, &c.Veeam.Password},
+		{"influx.token_file", c.Influx.TokenFile, prev.Influx.TokenFile, &c.Influx.Token},
+		{"influx.password_file", c.Influx.PasswordFile, prev.Influx.PasswordFile, &c.Influx.Password},
+	}
+
+	for _, f := range files {
+		if f.file == "" || f.file == f.prev {
+			continue
+		}
+
+		secret, err := readSecretFile(f.file)
+		if err != nil {
+			problems = append(problems, Problem{Field: f.field, Message: err.Error()})
+			continue
+		}
+
+		// the content of the file is the secret, even if it looks like a reference
+		*f.value = literalScheme + secret
+	}
+
+	return problems
+}
+
+// resolveSecrets replaces the secret references with the secrets they point to.
+// Only fields tagged with secret:"true" are resolved, failures are reported as problems of the field.
+func resolveSecrets(c *Config, resolvers map[string]secretResolver) []Problem {
+	var problems []Problem
+
+	// resolved secrets are not resolved again as vault references
+	resolved := make(map[string]struct{})
+
+	walkSecrets(reflect.ValueOf(c).Elem(), "", func(field string, v reflect.Value) {
+		if secret, ok := strings.CutPrefix(v.String(), literalScheme); ok {
+			v.SetString(secret)
+			resolved[field] = struct{}{}
+
+			return
+		}
+
+		for scheme, resolve := range resolvers {
+			ref, ok := strings.CutPrefix(v.String(), scheme)
+			if !ok {
+				continue
+			}
+
+			secret, err := resolve(ref)
+			if err != nil {
+				problems = append(problems, Problem{Field: field, Message: err.Error()})
+				return
+			}
+
+			v.SetString(secret)
+			resolved[field] = struct{}{}
+
+			return
+		}
+	})
+
+	// vault references are resolved last, so the vault credentials can be references themselves
+	return append(problems, resolveVaultSecrets(c, resolved)...)
+}
+
+// walkSecrets calls fn with the yaml path of every string field tagged with secret:"true"
+func walkSecrets(v reflect.Value, path string, fn func(field string, v reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkSecrets(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+
+			key := yamlKey(f)
+			if key == "" || key == "-" {
+				continue
+			}
+
+			if path != "" {
+				key = path + "." + key
+			}
+
+			if f.Tag.Get("secret") == "true" && f.Type.Kind() == reflect.String {
+				fn(key, v.Field(i))
+				continue
+			}
+
+			walkSecrets(v.Field(i), key, fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkSecrets(v.Index(i), fmt.Sprintf("%s.%d", path, i), fn)
+		}
+	}
+}
+
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret file: %v", err)
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func readSecretEnv(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("secret env var %s is not set", name)
+	}
+
+	return v, nil
+}
+
+// readSecretExec runs the command without a shell and returns its output
+func readSecretExec(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("secret command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		// the command line may hold sensitive arguments, only its name is reported
+		return "", fmt.Errorf("could not run secret command %s: %v", args[0], err)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// lookupSecretExec only checks the command can be found, it returns the reference unchanged
+func lookupSecretExec(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("secret command is empty")
+	}
+
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return "", fmt.Errorf("could not find secret command %s: %v", args[0], err)
+	}
+
+	return execScheme + command, nil
+}
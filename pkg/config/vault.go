@@ -0,0 +1,361 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// vault secret reference scheme, e.g. password: vault:secret/govein/veeam#password
+// reads the password key of the govein/veeam secret of the kv v2 engine mounted at secret
+const vaultScheme = "vault:"
+
+const (
+	VaultAuthToken      = "token"
+	VaultAuthAppRole    = "approle"
+	VaultAuthKubernetes = "kubernetes"
+
+	defaultVaultRefreshSeconds = 300
+	defaultKubernetesJWTFile   = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	vaultLoginRetry            = 30 * time.Second
+)
+
+type Vault struct {
+	Address   string    `yaml:"address"`
+	Namespace string    `yaml:"namespace,omitempty"`
+	CACert    string    `yaml:"ca_cert,omitempty"`
+	Auth      VaultAuth `yaml:"auth"`
+	// RefreshIntervalSeconds is how often the secrets are read again to pick up rotated values
+	RefreshIntervalSeconds int `yaml:"refresh_interval_seconds,omitempty"`
+}
+
+type VaultAuth struct {
+	// Method is one of token, approle or kubernetes
+	Method string `yaml:"method"`
+	// MountPath of the auth method, defaults to the method name
+	MountPath string `yaml:"mount_path,omitempty"`
+	// Token is used by the token method, VAULT_TOKEN is used when empty
+	Token    string `yaml:"token,omitempty" secret:"true"`
+	RoleID   string `yaml:"role_id,omitempty"`
+	SecretID string `yaml:"secret_id,omitempty" secret:"true"`
+	// Role and JWTFile are used by the kubernetes method
+	Role    string `yaml:"role,omitempty"`
+	JWTFile string `yaml:"jwt_file,omitempty"`
+}
+
+// vaultClient reads the vault secret references of the config and keeps its token alive
+type vaultClient struct {
+	conf  Vault
+	cl    *vault.Client
+	login *vault.Secret
+	// refs holds the vault reference of every resolved config field, values their last read value
+	refs   map[string]string
+	values map[string]string
+}
+
+func newVaultClient(ctx context.Context, conf Vault) (*vaultClient, error) {
+	vc := vault.DefaultConfig()
+	vc.Address = conf.Address
+
+	if conf.CACert != "" {
+		if err := vc.ConfigureTLS(&vault.TLSConfig{CACert: conf.CACert}); err != nil {
+			return nil, fmt.Errorf("could not configure vault tls: %v", err)
+		}
+	}
+
+	cl, err := vault.NewClient(vc)
+	if err != nil {
+		return nil, fmt.Errorf("could not create vault client: %v", err)
+	}
+
+	if conf.Namespace != "" {
+		cl.SetNamespace(conf.Namespace)
+	}
+
+	c := &vaultClient{
+		conf:   conf,
+		cl:     cl,
+		refs:   make(map[string]string),
+		values: make(map[string]string),
+	}
+
+	if err = c.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// authenticate logs into vault with the configured auth method
+func (c *vaultClient) authenticate(ctx context.Context) error {
+	mount := c.conf.Auth.MountPath
+	if mount == "" {
+		mount = c.conf.Auth.Method
+	}
+
+	var (
+		secret *vault.Secret
+		err    error
+	)
+
+	switch c.conf.Auth.Method {
+	case VaultAuthToken:
+		if c.conf.Auth.Token != "" {
+			c.cl.SetToken(c.conf.Auth.Token)
+		}
+
+		if secret, err = c.cl.Auth().Token().LookupSelfWithContext(ctx); err != nil {
+			return fmt.Errorf("could not look up vault token: %v", err)
+		}
+
+		// the lookup response holds the token in its data, the lifetime watcher expects it as auth
+		renewable, _ := secret.TokenIsRenewable()
+		ttl, _ := secret.TokenTTL()
+		secret = &vault.Secret{Auth: &vault.SecretAuth{
+			ClientToken:   c.cl.Token(),
+			Renewable:     renewable,
+			LeaseDuration: int(ttl.Seconds()),
+		}}
+	case VaultAuthAppRole:
+		secret, err = c.cl.Logical().WriteWithContext(ctx, "auth/"+mount+"/login", map[string]any{
+			"role_id":   c.conf.Auth.RoleID,
+			"secret_id": c.conf.Auth.SecretID,
+		})
+	case VaultAuthKubernetes:
+		jwtFile := c.conf.Auth.JWTFile
+		if jwtFile == "" {
+			jwtFile = defaultKubernetesJWTFile
+		}
+
+		jwt, readErr := os.ReadFile(jwtFile)
+		if readErr != nil {
+			return fmt.Errorf("could not read kubernetes service account token: %v", readErr)
+		}
+
+		secret, err = c.cl.Logical().WriteWithContext(ctx, "auth/"+mount+"/login", map[string]any{
+			"role": c.conf.Auth.Role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		})
+	default:
+		return fmt.Errorf("unsupported vault auth method %q", c.conf.Auth.Method)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not login to vault: %v", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return fmt.Errorf("could not login to vault: no auth info returned")
+	}
+
+	c.cl.SetToken(secret.Auth.ClientToken)
+	c.login = secret
+
+	return nil
+}
+
+// read returns the value a vault:<mount>/<path>#<key> reference points to
+func (c *vaultClient) read(ctx context.Context, ref string) (string, error) {
+	secretPath, key, ok := strings.Cut(ref, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("vault reference must look like vault:<mount>/<path>#<key>, got %q", vaultScheme+ref)
+	}
+
+	mount, secretPath, ok := strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !ok || secretPath == "" {
+		return "", fmt.Errorf("vault reference must look like vault:<mount>/<path>#<key>, got %q", vaultScheme+ref)
+	}
+
+	s, err := c.cl.KVv2(mount).Get(ctx, secretPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read vault secret %s/%s: %v", mount, secretPath, err)
+	}
+
+	v, ok := s.Data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s/%s has no key %s", mount, secretPath, key)
+	}
+
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s/%s key %s is not a string", mount, secretPath, key)
+	}
+
+	return str, nil
+}
+
+// resolveVaultSecrets logs into vault, when it is configured, and resolves the vault secret references
+// of the fields not resolved already
+func resolveVaultSecrets(c *Config, resolved map[string]struct{}) []Problem {
+	var (
+		problems []Problem
+		vc       *vaultClient
+	)
+
+	failed := false
+	walkSecrets(reflect.ValueOf(c).Elem(), "", func(field string, v reflect.Value) {
+		ref, ok := strings.CutPrefix(v.String(), vaultScheme)
+		if _, done := resolved[field]; !ok || done || failed {
+			return
+		}
+
+		if c.Vault == nil {
+			problems = append(problems, Problem{Field: field, Message: "vault reference used, but vault is not configured"})
+			return
+		}
+
+		if vc == nil {
+			var err error
+			if vc, err = newVaultClient(context.Background(), *c.Vault); err != nil {
+				// report the login failure only once
+				problems = append(problems, Problem{Field: "vault", Message: err.Error()})
+				failed = true
+				return
+			}
+		}
+
+		secret, err := vc.read(context.Background(), ref)
+		if err != nil {
+			problems = append(problems, Problem{Field: field, Message: err.Error()})
+			return
+		}
+
+		// remembered for WatchVault
+		vc.refs[field] = ref
+		vc.values[field] = secret
+		v.SetString(secret)
+	})
+
+	c.vault = vc
+
+	return problems
+}
+
+// WatchVault keeps the vault token renewed, logging in again once it can not be renewed anymore,
+// and reads the vault secret references of the config on the refresh interval.
+// fn is called with the config field and its new value every time a secret is rotated in vault.
+// It blocks until the context is done, and returns right away if the config uses no vault secrets.
+func (c Config) WatchVault(ctx context.Context, log *slog.Logger, fn func(field, value string)) {
+	if c.vault == nil || len(c.vault.refs) == 0 {
+		return
+	}
+
+	vc := c.vault
+	log = log.WithGroup("vault")
+
+	go vc.keepAlive(ctx, log)
+
+	interval := time.Duration(vc.conf.RefreshIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultVaultRefreshSeconds * time.Second
+	}
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		for field, ref := range vc.refs {
+			v, err := vc.read(ctx, ref)
+			if err != nil {
+				log.Error("Could not refresh vault secret", "field", field, "err", err)
+				continue
+			}
+
+			if v == vc.values[field] {
+				continue
+			}
+
+			log.Info("Vault secret rotated", "field", field)
+			vc.values[field] = v
+			fn(field, v)
+		}
+	}
+}
+
+// keepAlive renews the vault token until it reaches its max ttl, then logs in again.
+// A token which is not renewable is replaced by logging in again before its ttl passed.
+func (c *vaultClient) keepAlive(ctx context.Context, log *slog.Logger) {
+	for {
+		if c.login.Auth.Renewable {
+			w, err := c.cl.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: c.login})
+			if err != nil {
+				log.Error("Could not watch vault token", "err", err)
+				return
+			}
+
+			go w.Start()
+
+			if !c.watch(ctx, log, w) {
+				return
+			}
+		} else {
+			ttl := time.Duration(c.login.Auth.LeaseDuration) * time.Second
+			if ttl <= 0 {
+				log.Debug("Vault token does not expire")
+				return
+			}
+
+			// a static token can not be replaced by logging in again
+			if c.conf.Auth.Method == VaultAuthToken {
+				log.Warn("Vault token is not renewable, secrets will not be refreshed once it expires", "ttl", ttl.String())
+				return
+			}
+
+			// two thirds of the ttl, as the lifetime watcher renews renewable tokens
+			log.Debug("Vault token is not renewable, logging in again before it expires", "ttl", ttl.String())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ttl * 2 / 3):
+			}
+		}
+
+		for {
+			err := c.authenticate(ctx)
+			if err == nil {
+				log.Info("Logged into vault again")
+				break
+			}
+
+			log.Error("Could not login to vault", "err", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(vaultLoginRetry):
+			}
+		}
+	}
+}
+
+// watch waits for the lifetime watcher to finish, it returns false if the context is done
+func (c *vaultClient) watch(ctx context.Context, log *slog.Logger, w *vault.LifetimeWatcher) bool {
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case err := <-w.DoneCh():
+			if err != nil {
+				log.Warn("Vault token renewal stopped", "err", err)
+			}
+			return true
+		case r := <-w.RenewCh():
+			log.Debug("Vault token renewed", "at", r.RenewedAt)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault serves the approle login and kv v2 reads of a vault server
+type fakeVault struct {
+	mu       sync.Mutex
+	password string
+	// lease is the ttl of the not renewable login tokens in seconds, an hour when zero
+	lease  int
+	logins int
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/auth/approle/login":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		if body["role_id"] != "govein" || body["secret_id"] != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or secret id"]}`))
+			return
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.logins++
+		lease := f.lease
+		if lease == 0 {
+			lease = 3600
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": "test-token", "renewable": false, "lease_duration": lease},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/secret/data/govein/veeam":
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data":     map[string]any{"username": "admin", "password": f.password},
+				"metadata": map[string]any{"version": 1},
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}
+}
+
+func (f *fakeVault) loginCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logins
+}
+
+func (f *fakeVault) rotate(password string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.password = password
+}
+
+func vaultConfig(addr string) string {
+	content := strings.Replace(validConfig, "  username: admin\n", "  username: vault:secret/govein/veeam#username\n", 1)
+	content = strings.Replace(content, "  password: secret\n", "  password: vault:secret/govein/veeam#password\n", 1)
+
+	return content + `vault:
+  address: ` + addr + `
+  refresh_interval_seconds: 1
+  auth:
+    method: approle
+    role_id: govein
+    secret_id: env:TEST_GOVEIN_SECRET_ID
+`
+}
+
+func TestVaultSecrets(t *testing.T) {
+	fv := &fakeVault{password: "from-vault"}
+	srv := httptest.NewServer(fv)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("TEST_GOVEIN_SECRET_ID", "s3cr3t")
+
+	conf, err := LoadFile(writeConfig(t, vaultConfig(srv.URL)))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	if conf.Veeam.Username != "admin" || conf.Veeam.Password != "from-vault" {
+		t.Fatalf("unexpected credentials %q %q", conf.Veeam.Username, conf.Veeam.Password)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rotated := make(chan string, 1)
+	go conf.WatchVault(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), func(field, value string) {
+		if field == "veeam.password" {
+			rotated <- value
+		}
+	})
+
+	fv.rotate("rotated")
+
+	select {
+	case v := <-rotated:
+		if v != "rotated" {
+			t.Errorf("expected rotated password, got %q", v)
+		}
+	case <-ctx.Done():
+		t.Fatal("rotated password was not reported")
+	}
+}
+
+func TestVaultLoginAgain(t *testing.T) {
+	fv := &fakeVault{password: "from-vault", lease: 3}
+	srv := httptest.NewServer(fv)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("TEST_GOVEIN_SECRET_ID", "s3cr3t")
+
+	conf, err := LoadFile(writeConfig(t, vaultConfig(srv.URL)))
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go conf.WatchVault(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), func(string, string) {})
+
+	// the token is not renewable, so a new one is requested before its 3s ttl passed
+	for fv.loginCount() < 2 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("expected to login to vault again before the token expired")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestVaultLoginProblem(t *testing.T) {
+	srv := httptest.NewServer(&fakeVault{})
+	t.Cleanup(srv.Close)
+
+	t.Setenv("TEST_GOVEIN_SECRET_ID", "wrong")
+
+	_, err := LoadFile(writeConfig(t, vaultConfig(srv.URL)))
+	if err == nil || !strings.Contains(err.Error(), "vault: could not login to vault") {
+		t.Fatalf("expected vault login problem, got %v", err)
+	}
+
+	// the login failure is reported once, not for every vault reference
+	if strings.Count(err.Error(), "could not login") != 1 {
+		t.Errorf("expected a single login problem, got %v", err)
+	}
+}
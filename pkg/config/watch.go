@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce groups the events of a single save, editors and kubernetes write files in several steps
+const watchDebounce = 500 * time.Millisecond
+
+// Watch calls fn every time the config file changes, until the context is done.
+// The directory is watched rather than the file, so files replaced by editors or
+// kubernetes config map updates, which swap a ..data symlink, are picked up as well.
+func Watch(ctx context.Context, path string, log *slog.Logger, fn func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create config file watcher: %v", err)
+	}
+
+	path = filepath.Clean(path)
+	if err = w.Add(filepath.Dir(path)); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("could not watch config file: %v", err)
+	}
+
+	go func() {
+		defer w.Close()
+
+		var debounce <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+
+				name := filepath.Clean(e.Name)
+				if name != path && filepath.Base(name) != "..data" {
+					continue
+				}
+
+				if e.Has(fsnotify.Write) || e.Has(fsnotify.Create) || e.Has(fsnotify.Rename) {
+					debounce = time.After(watchDebounce)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Error("Config file watcher error", "err", err)
+			case <-debounce:
+				debounce = nil
+				fn()
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package dryrun prints the points the influx sink would write, to preview filters and schema changes
+// without writing to a bucket
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// DryRun builds the points the same way the influx sink does, custom tags and the naming schema included,
+// and prints them instead of writing them
+type DryRun struct {
+	w *Writer
+	i *influx.Influx
+}
+
+func New(ctx context.Context, conf config.Config, log *slog.Logger, out io.Writer) (*DryRun, error) {
+	w := NewWriter(out, conf.DryRun)
+
+	i, err := influx.NewWithWriter(ctx, conf, log, w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DryRun{w: w, i: i}, nil
+}
+
+func (d *DryRun) Name() string {
+	return "dry-run"
+}
+
+// Store prints the points of the collected data
+func (d *DryRun) Store(v veeam.Veeam) error {
+	return d.i.Store(v)
+}
+
+func (d *DryRun) StoreStatus(v veeam.Veeam) error {
+	return d.i.StoreStatus(v)
+}
+
+func (d *DryRun) SetConfig(conf config.Config) error {
+	return d.i.SetConfig(conf)
+}
+
+func (d *DryRun) Ping() error {
+	return nil
+}
+
+func (d *DryRun) Close() error {
+	return nil
+}
+
+// Summary prints the number of points printed per measurement
+func (d *DryRun) Summary() error {
+	return d.w.Summary()
+}
+
+// Writer is an influx.Writer printing the points as text or line protocol, and counting them per measurement
+type Writer struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format string
+	counts map[string]int
+}
+
+// NewWriter prints the points to out in the format, text or line_protocol
+func NewWriter(out io.Writer, format string) *Writer {
+	return &Writer{
+		out:    out,
+		format: format,
+		counts: make(map[string]int),
+	}
+}
+
+func (w *Writer) Ping(context.Context) (string, error) {
+	return "", nil
+}
+
+func (w *Writer) WritePoints(_ context.Context, points ...*write.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, p := range points {
+		w.counts[p.Name()]++
+	}
+
+	if w.format == config.DryRunLineProtocol {
+		b, err := influx.Encode(points...)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.out.Write(b)
+
+		return err
+	}
+
+	for _, p := range points {
+		if _, err := io.WriteString(w.out, text(p)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) Close() error {
+	return nil
+}
+
+// text returns the point with a tag or field per line, points without a time are stored with the write time
+func text(p *write.Point) string {
+	var b strings.Builder
+
+	b.WriteString(p.Name())
+	if !p.Time().IsZero() {
+		fmt.Fprintf(&b, " @ %s", p.Time().UTC().Format(time.RFC3339))
+	}
+	b.WriteString("\n")
+
+	for _, t := range p.TagList() {
+		fmt.Fprintf(&b, "  tag   %s = %q\n", t.Key, t.Value)
+	}
+
+	for _, f := range p.FieldList() {
+		fmt.Fprintf(&b, "  field %s = %v\n", f.Key, f.Value)
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// Summary prints the number of points written per measurement, in the format of the points
+func (w *Writer) Summary() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names := make([]string, 0, len(w.counts))
+	width, total := 0, 0
+	for name, n := range w.counts {
+		names = append(names, name)
+		width = max(width, len(name))
+		total += n
+	}
+	slices.Sort(names)
+
+	// line protocol output stays parsable, the summary is made of comments
+	prefix := ""
+	if w.format == config.DryRunLineProtocol {
+		prefix = "# "
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sSummary\n", prefix)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %-*s %d\n", prefix, width, name, w.counts[name])
+	}
+	fmt.Fprintf(&b, "%s  %-*s %d\n", prefix, width, "total", total)
+
+	_, err := io.WriteString(w.out, b.String())
+
+	return err
+}
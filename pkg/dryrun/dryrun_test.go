@@ -0,0 +1,172 @@
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/schema"
+	"github.com/ZeljkoBenovic/govein/pkg/sink/sinktest"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+	lp "github.com/influxdata/line-protocol"
+)
+
+func dryRun(t *testing.T, v veeam.Veeam, conf config.Config) string {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	d, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.Store(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.Summary(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.String()
+}
+
+func TestText(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	conf.DryRun = config.DryRunText
+	conf.Schema.Profile = schema.SnakeCase
+
+	out := dryRun(t, v, conf)
+
+	// the points are renamed to the schema
+	if !strings.Contains(out, "veeam_vbr_info\n  tag   ") || strings.Contains(out, "veeamVBRId") {
+		t.Errorf("expected the snake_case vbr info point, got\n%s", out)
+	}
+
+	summary := out[strings.Index(out, "Summary\n"):]
+	if !strings.Contains(summary, "veeam_vbr_info ") || !strings.Contains(summary, "total") {
+		t.Errorf("unexpected summary\n%s", summary)
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	conf.DryRun = config.DryRunLineProtocol
+
+	out := dryRun(t, v, conf)
+
+	// the summary is made of comments, so the output can be parsed and imported as it is
+	p := lp.NewStreamParser(strings.NewReader(out))
+	counts := make(map[string]int)
+	for {
+		m, err := p.Next()
+		if errors.Is(err, lp.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("could not parse dry-run output: %v", err)
+		}
+
+		counts[m.Name()]++
+	}
+
+	if counts["veeam_vbr_backupobjects"] != len(v.BackupObjects.Data) {
+		t.Errorf("expected %d backup objects, got %v", len(v.BackupObjects.Data), counts)
+	}
+
+	if counts["veeam_vbr_collectors"] != len(v.Collectors) || len(v.Collectors) == 0 {
+		t.Errorf("expected a state point per collector, got %v", counts)
+	}
+
+	if !strings.Contains(out, "# Summary\n") {
+		t.Errorf("expected the summary at the end, got\n%s", out)
+	}
+}
+
+func TestStoreStatus(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	srv.InjectError("/api/v1/backupInfrastructure/wanAccelerators", http.StatusInternalServerError, "UnknownError", -1)
+
+	conf := srv.Config()
+	conf.DryRun = config.DryRunLineProtocol
+
+	v, err := veeam.NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = v.Collect(); err == nil {
+		t.Fatal("expected the server error to fail the cycle")
+	}
+
+	var out bytes.Buffer
+
+	d, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.StoreStatus(*v); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed cycle stores the collector states and api client stats only
+	var failed, collectors, api int
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		switch {
+		case strings.HasPrefix(line, "veeam_vbr_collectors,"):
+			collectors++
+			if strings.Contains(line, "veeamVBRCollector=wan_accelerators ") && strings.Contains(line, "veeamVBRCollectorState=2i") {
+				failed++
+			}
+		case strings.HasPrefix(line, "veeam_vbr_api,"):
+			api++
+		default:
+			t.Errorf("unexpected point %s", line)
+		}
+	}
+
+	if failed != 1 || collectors != len(v.Collectors) || api != 1 {
+		t.Errorf("expected the failed wan accelerators collector and the api stats, got\n%s", out.String())
+	}
+}
+
+func TestSkippedCollectors(t *testing.T) {
+	v, conf := sinktest.Collected(t, func(srv *veeamtest.Server) {
+		for _, path := range []string{
+			"/api/v1/configBackup",
+			"/api/v1/backupInfrastructure/proxies/states",
+			"/api/v1/inventory/vmware/hosts/vcenter.lab.local",
+		} {
+			srv.InjectError(path, http.StatusForbidden, "Forbidden", -1)
+		}
+	})
+	conf.DryRun = config.DryRunLineProtocol
+
+	out := dryRun(t, v, conf)
+
+	// the data of the skipped collectors is not stored as never backed up, offline or unprotected
+	for _, measurement := range []string{
+		"veeam_vbr_config_backup", "veeam_vbr_proxy_states", "veeam_vbr_protection_coverage", "veeam_vbr_unprotected_objects",
+	} {
+		if strings.Contains(out, measurement+",") {
+			t.Errorf("expected no %s points, got\n%s", measurement, out)
+		}
+	}
+
+	if strings.Count(out, "veeamVBRCollectorState=1i") != 3 {
+		t.Errorf("expected 3 skipped collectors, got\n%s", out)
+	}
+}
@@ -0,0 +1,78 @@
+// Package file writes the data of every cycle into rotating files, as InfluxDB line protocol, JSON Lines or csv,
+// so it can be shipped from sites without network access and imported later
+package file
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// File stores the same points as the influx sink, custom tags and the naming schema included
+type File struct {
+	log *slog.Logger
+	w   *Writer
+	i   *influx.Influx
+}
+
+func New(ctx context.Context, conf config.Config, log *slog.Logger) (*File, error) {
+	if conf.File == nil {
+		return nil, fmt.Errorf("file sink is not configured")
+	}
+
+	w, err := NewWriter(*conf.File)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := influx.NewWithWriter(ctx, conf, log, w)
+	if err != nil {
+		return nil, err
+	}
+
+	format := conf.File.Format
+	if format == "" {
+		format = config.FileFormatLineProtocol
+	}
+
+	log.Info("File sink created", "dir", conf.File.Dir, "format", format, "gzip", conf.File.Gzip)
+
+	return &File{
+		log: log,
+		w:   w,
+		i:   i,
+	}, nil
+}
+
+func (f *File) Name() string {
+	return config.SinkFile
+}
+
+// Store appends the data collected by a cycle to the active files
+func (f *File) Store(v veeam.Veeam) error {
+	return f.i.Store(v)
+}
+
+func (f *File) StoreStatus(v veeam.Veeam) error {
+	return f.i.StoreStatus(v)
+}
+
+// SetConfig swaps the custom tags and the schema, file settings need a new sink instead
+func (f *File) SetConfig(conf config.Config) error {
+	return f.i.SetConfig(conf)
+}
+
+func (f *File) Ping() error {
+	_, err := f.w.Ping(context.Background())
+
+	return err
+}
+
+// Close writes the points left of the cycle, files are not kept open between cycles
+func (f *File) Close() error {
+	return f.w.Close()
+}
@@ -0,0 +1,236 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/sink/sinktest"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// store stores the data once with the file settings and returns the directory of the files
+func store(t *testing.T, v veeam.Veeam, conf config.Config, f config.File) string {
+	t.Helper()
+
+	f.Dir = filepath.Join(t.TempDir(), "export")
+	conf.File = &f
+
+	s, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Store(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// points collects the points written by Import
+type points struct {
+	written []*write.Point
+}
+
+func (p *points) Ping(context.Context) (string, error) { return "", nil }
+
+func (p *points) WritePoints(_ context.Context, points ...*write.Point) error {
+	p.written = append(p.written, points...)
+	return nil
+}
+
+func (p *points) Close() error { return nil }
+
+func TestLineProtocolImport(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	dir := store(t, v, conf, config.File{Gzip: true})
+
+	path := filepath.Join(dir, "govein.lp.gz")
+	lines := strings.Split(strings.TrimSpace(readFile(t, path)), "\n")
+
+	var p points
+	n, err := Import(context.Background(), &p, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(lines) || len(p.written) != len(lines) {
+		t.Fatalf("expected %d points imported, got %d", len(lines), n)
+	}
+
+	// every point has the collection time, so it is not imported with the import time
+	for _, pt := range p.written {
+		if pt.Time().IsZero() {
+			t.Errorf("unexpected time %v of %s", pt.Time(), pt.Name())
+		}
+	}
+
+	if p.written[0].Name() != "veeam_vbr_info" {
+		t.Errorf("expected the vbr info first, got %s", p.written[0].Name())
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	dir := store(t, v, conf, config.File{Format: config.FileFormatJSONL})
+
+	sc := bufio.NewScanner(strings.NewReader(readFile(t, filepath.Join(dir, "govein.jsonl"))))
+
+	measurements := make(map[string]int)
+	for sc.Scan() {
+		var r record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Time.IsZero() || len(r.Fields) == 0 {
+			t.Errorf("unexpected record %s", sc.Text())
+		}
+
+		measurements[r.Measurement]++
+	}
+
+	if measurements["veeam_vbr_info"] != 1 || measurements["veeam_vbr_backupobjects"] != len(v.BackupObjects.Data) {
+		t.Errorf("unexpected measurements %v", measurements)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	dir := store(t, v, conf, config.File{Format: config.FileFormatCSV})
+
+	rows, err := csv.NewReader(strings.NewReader(readFile(t, filepath.Join(dir, "veeam_vbr_info.csv")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"time", "veeamDatabaseVendor", "veeamVBR", "veeamVBRId", "veeamVBRName", "veeamVBRVersion", "vbr"}
+	if len(rows) != 2 || strings.Join(rows[0], ",") != strings.Join(want, ",") {
+		t.Fatalf("expected header %v and a row, got %v", want, rows)
+	}
+
+	if rows[1][2] != conf.Veeam.Host || rows[1][6] != "1" {
+		t.Errorf("unexpected row %v", rows[1])
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if len(files) < 5 {
+		t.Errorf("expected a file per measurement, got %v", files)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	conf := config.File{Dir: dir, Format: config.FileFormatCSV, Gzip: true, MaxFiles: 2}
+
+	cycle := func(w *Writer, fields ...string) {
+		t.Helper()
+
+		p := influxdb2.NewPointWithMeasurement("m").AddTag("host", "vbr")
+		for _, f := range fields {
+			p.AddField(f, 1)
+		}
+
+		if err := w.WritePoints(context.Background(), p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := func() []string {
+		t.Helper()
+
+		m, _ := filepath.Glob(filepath.Join(dir, "*"))
+		for i := range m {
+			m[i] = filepath.Base(m[i])
+		}
+		return m
+	}
+
+	w, err := NewWriter(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same header appends to the active file, in its own gzip member
+	cycle(w, "a")
+	cycle(w, "a")
+
+	if got := files(); len(got) != 1 || got[0] != "m.csv.gz" {
+		t.Fatalf("expected a single active file, got %v", got)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "m.csv.gz")); strings.Count(got, "\n") != 3 {
+		t.Errorf("expected the header and two rows, got %q", got)
+	}
+
+	// a new header rotates
+	cycle(w, "a", "b")
+
+	// so does a restart
+	w, err = NewWriter(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycle(w, "a", "b")
+
+	// and a full file
+	if err = os.Truncate(filepath.Join(dir, "m.csv.gz"), defaultMaxSizeMB*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	cycle(w, "a", "b")
+
+	// the oldest of the 3 rotated files is removed
+	got := files()
+	if len(got) != 3 || got[2] != "m.csv.gz" {
+		t.Fatalf("expected 2 rotated files and the active one, got %v", got)
+	}
+
+	for _, name := range got[:2] {
+		if !strings.HasPrefix(name, "m.") || !strings.HasSuffix(name, ".csv.gz") {
+			t.Errorf("unexpected rotated file %s", name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// extension returns the file extension of the format
+func extension(format string) string {
+	switch format {
+	case config.FileFormatJSONL:
+		return ".jsonl"
+	case config.FileFormatCSV:
+		return ".csv"
+	default:
+		return ".lp"
+	}
+}
+
+func writeLineProtocol(out io.Writer, points []*write.Point) error {
+	b, err := influx.Encode(points...)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(b)
+
+	return err
+}
+
+// record is a point in a JSON Lines file
+type record struct {
+	Measurement string            `json:"measurement"`
+	Time        time.Time         `json:"time"`
+	Tags        map[string]string `json:"tags"`
+	Fields      map[string]any    `json:"fields"`
+}
+
+func writeJSONL(out io.Writer, points []*write.Point) error {
+	enc := json.NewEncoder(out)
+
+	for _, p := range points {
+		r := record{
+			Measurement: p.Name(),
+			Time:        p.Time(),
+			Tags:        make(map[string]string, len(p.TagList())),
+			Fields:      make(map[string]any, len(p.FieldList())),
+		}
+
+		for _, t := range p.TagList() {
+			r.Tags[t.Key] = t.Value
+		}
+
+		for _, f := range p.FieldList() {
+			r.Fields[f.Key] = f.Value
+		}
+
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("could not encode point %s: %v", p.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// byMeasurement groups the points by measurement, in the order the measurements were first written
+func byMeasurement(points []*write.Point) ([]string, map[string][]*write.Point) {
+	var names []string
+	groups := make(map[string][]*write.Point)
+
+	for _, p := range points {
+		if _, ok := groups[p.Name()]; !ok {
+			names = append(names, p.Name())
+		}
+
+		groups[p.Name()] = append(groups[p.Name()], p)
+	}
+
+	return names, groups
+}
+
+// csvHeader returns the columns of the points of a measurement: the time, then the sorted tag and field keys
+func csvHeader(points []*write.Point) []string {
+	var tagKeys, fieldKeys []string
+
+	for _, p := range points {
+		for _, t := range p.TagList() {
+			if !slices.Contains(tagKeys, t.Key) {
+				tagKeys = append(tagKeys, t.Key)
+			}
+		}
+
+		for _, f := range p.FieldList() {
+			if !slices.Contains(fieldKeys, f.Key) {
+				fieldKeys = append(fieldKeys, f.Key)
+			}
+		}
+	}
+
+	slices.Sort(tagKeys)
+	slices.Sort(fieldKeys)
+
+	return append(append([]string{"time"}, tagKeys...), fieldKeys...)
+}
+
+// writeCSV writes a row per point with the columns of the header, writing the header first if withHeader is set.
+// Tags and fields missing from a point are left empty.
+func writeCSV(out io.Writer, points []*write.Point, header []string, withHeader bool) error {
+	w := csv.NewWriter(out)
+
+	if withHeader {
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range points {
+		values := make(map[string]string, len(p.TagList())+len(p.FieldList()))
+		for _, t := range p.TagList() {
+			values[t.Key] = t.Value
+		}
+
+		for _, f := range p.FieldList() {
+			values[f.Key] = csvValue(f.Value)
+		}
+
+		row := make([]string, len(header))
+		row[0] = p.Time().UTC().Format(time.RFC3339Nano)
+		for i, col := range header[1:] {
+			row[i+1] = values[col]
+		}
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	lp "github.com/influxdata/line-protocol"
+)
+
+const importBatchSize = 5000
+
+// Import writes the points of a line protocol file, gzipped or not, to w in batches
+// and returns how many points were written
+func Import(ctx context.Context, w influx.Writer, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("could not open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return 0, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	var (
+		written int
+		batch   = make([]*write.Point, 0, importBatchSize)
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := w.WritePoints(ctx, batch...); err != nil {
+			return fmt.Errorf("could not import %s: %v", path, err)
+		}
+
+		written += len(batch)
+		batch = batch[:0]
+
+		return nil
+	}
+
+	p := lp.NewStreamParser(r)
+
+	for {
+		m, err := p.Next()
+		if errors.Is(err, lp.EOF) {
+			break
+		}
+
+		if err != nil {
+			return written, fmt.Errorf("could not parse %s: %v", path, err)
+		}
+
+		batch = append(batch, point(m))
+
+		if len(batch) == importBatchSize {
+			if err = flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, flush()
+}
+
+// decompress returns the reader of the file, gzipped files are detected by their magic bytes
+func decompress(f io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(f)
+
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+
+	return br, nil
+}
+
+func point(m lp.Metric) *write.Point {
+	tags := make(map[string]string, len(m.TagList()))
+	for _, t := range m.TagList() {
+		tags[t.Key] = t.Value
+	}
+
+	fields := make(map[string]any, len(m.FieldList()))
+	for _, f := range m.FieldList() {
+		fields[f.Key] = f.Value
+	}
+
+	return write.NewPoint(m.Name(), tags, fields, m.Time())
+}
@@ -0,0 +1,265 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+const (
+	defaultMaxSizeMB = 100
+	// activeName is the file line protocol and JSON Lines are written to, csv files are named after the measurement
+	activeName = "govein"
+	// rotatedTime is sortable, so the oldest rotated files come first
+	rotatedTime = "20060102T150405Z"
+)
+
+// Writer is an influx.Writer buffering the points of a cycle, which are appended to the active files on Close
+type Writer struct {
+	mu   sync.Mutex
+	conf config.File
+
+	// cycle is the time of the points written without one, set by the first write of the cycle
+	cycle  time.Time
+	points []*write.Point
+	// active are the files written since the start, files found on start are rotated first
+	active map[string]*activeFile
+}
+
+type activeFile struct {
+	created time.Time
+	// header of a csv file, a new header rotates the file
+	header []string
+}
+
+// NewWriter creates the directory of the files if it does not exist
+func NewWriter(conf config.File) (*Writer, error) {
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create file sink directory: %v", err)
+	}
+
+	return &Writer{
+		conf:   conf,
+		active: make(map[string]*activeFile),
+	}, nil
+}
+
+// Ping checks the directory of the files is still there
+func (w *Writer) Ping(_ context.Context) (string, error) {
+	fi, err := os.Stat(w.conf.Dir)
+	if err != nil {
+		return "", err
+	}
+
+	if !fi.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", w.conf.Dir)
+	}
+
+	return "", nil
+}
+
+func (w *Writer) WritePoints(_ context.Context, points ...*write.Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cycle.IsZero() {
+		w.cycle = time.Now()
+	}
+
+	// replayed files must keep the collection time, not the import time
+	for _, p := range points {
+		if p.Time().IsZero() {
+			p.SetTime(w.cycle)
+		}
+	}
+
+	w.points = append(w.points, points...)
+
+	return nil
+}
+
+// Close appends the points of the cycle to the active files, rotating them first if needed
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	points := w.points
+	w.points = nil
+	w.cycle = time.Time{}
+
+	if len(points) == 0 {
+		return nil
+	}
+
+	switch w.conf.Format {
+	case config.FileFormatJSONL:
+		return w.append(activeName, nil, func(out io.Writer, _ bool) error {
+			return writeJSONL(out, points)
+		})
+	case config.FileFormatCSV:
+		names, groups := byMeasurement(points)
+
+		var errs []error
+		for _, name := range names {
+			header := csvHeader(groups[name])
+			if err := w.append(name, header, func(out io.Writer, created bool) error {
+				return writeCSV(out, groups[name], header, created)
+			}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	default:
+		return w.append(activeName, nil, func(out io.Writer, _ bool) error {
+			return writeLineProtocol(out, points)
+		})
+	}
+}
+
+func (w *Writer) path(name string) string {
+	ext := extension(w.conf.Format)
+	if w.conf.Gzip {
+		ext += ".gz"
+	}
+
+	return filepath.Join(w.conf.Dir, name+ext)
+}
+
+// append opens the active file of the name, rotating it if it is full, too old or has another header,
+// and appends to it what write writes. Gzipped files get a gzip member per append.
+func (w *Writer) append(name string, header []string, write func(out io.Writer, created bool) error) error {
+	path := w.path(name)
+
+	rotate, err := w.needsRotation(name, path, header)
+	if err != nil {
+		return err
+	}
+
+	if rotate {
+		if err = w.rotate(name, path); err != nil {
+			return err
+		}
+	}
+
+	_, err = os.Stat(path)
+	created := errors.Is(err, os.ErrNotExist)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if created {
+		w.active[name] = &activeFile{created: time.Now(), header: header}
+	}
+
+	var out io.Writer = f
+	if w.conf.Gzip {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		out = gz
+	}
+
+	if err = write(out, created); err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+
+	if gz, ok := out.(*gzip.Writer); ok {
+		if err = gz.Close(); err != nil {
+			return fmt.Errorf("could not write %s: %v", path, err)
+		}
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (w *Writer) needsRotation(name, path string, header []string) (bool, error) {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	a, ok := w.active[name]
+	if !ok {
+		// left over from a previous run, its header and age are unknown
+		return true, nil
+	}
+
+	maxSize := int64(w.conf.MaxSizeMB)
+	if maxSize == 0 {
+		maxSize = defaultMaxSizeMB
+	}
+
+	if fi.Size() >= maxSize*1024*1024 {
+		return true, nil
+	}
+
+	if w.conf.MaxAgeHours > 0 && time.Since(a.created) >= time.Duration(w.conf.MaxAgeHours)*time.Hour {
+		return true, nil
+	}
+
+	return !slices.Equal(a.header, header), nil
+}
+
+// rotate renames the active file to a timestamped name and removes the oldest rotated files past max_files
+func (w *Writer) rotate(name, path string) error {
+	ext := path[len(filepath.Join(w.conf.Dir, name)):]
+	stamp := time.Now().UTC().Format(rotatedTime)
+
+	rotated := filepath.Join(w.conf.Dir, name+"."+stamp+ext)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(rotated); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		// the counter sorts after the first file of the second
+		rotated = filepath.Join(w.conf.Dir, fmt.Sprintf("%s.%s_%d%s", name, stamp, n, ext))
+	}
+
+	if err := os.Rename(path, rotated); err != nil {
+		return fmt.Errorf("could not rotate %s: %v", path, err)
+	}
+
+	delete(w.active, name)
+
+	if w.conf.MaxFiles <= 0 {
+		return nil
+	}
+
+	old, err := filepath.Glob(filepath.Join(w.conf.Dir, name+".*"+ext))
+	if err != nil {
+		return err
+	}
+
+	slices.Sort(old)
+
+	for len(old) > w.conf.MaxFiles {
+		if err = os.Remove(old[0]); err != nil {
+			return fmt.Errorf("could not remove rotated file: %v", err)
+		}
+
+		old = old[1:]
+	}
+
+	return nil
+}
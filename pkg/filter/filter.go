@@ -0,0 +1,198 @@
+// Package filter decides which collected veeam objects are kept, using include and exclude rules
+// matching the job name, session type, platform, repository, proxy or object path of the objects.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Field string
+
+const (
+	JobName     Field = "job_name"
+	SessionType Field = "session_type"
+	Platform    Field = "platform"
+	Repository  Field = "repository"
+	Proxy       Field = "proxy"
+	ObjectPath  Field = "object_path"
+
+	// InventoryPath is the <host>\<name> path of a virtual machine, rules can not select it.
+	// Object path rules match it along with the object path, as the inventory does not report folders.
+	InventoryPath Field = "inventory_path"
+)
+
+// Fields lists every field rules can match
+var Fields = []Field{JobName, SessionType, Platform, Repository, Proxy, ObjectPath}
+
+// Rule matches a field of the collected objects with either a glob or a regular expression.
+// Globs are case-insensitive, * matches any characters including path separators and ? matches a single character.
+type Rule struct {
+	Field Field  `yaml:"field"`
+	Glob  string `yaml:"glob,omitempty"`
+	Regex string `yaml:"regex,omitempty"`
+}
+
+// Compile checks the rule and returns its regular expression
+func (r Rule) Compile() (*regexp.Regexp, error) {
+	known := false
+	for _, f := range Fields {
+		if r.Field == f {
+			known = true
+		}
+	}
+
+	if !known {
+		return nil, fmt.Errorf("unknown field %q, must be one of %s", r.Field, fieldNames())
+	}
+
+	switch {
+	case r.Glob != "" && r.Regex != "":
+		return nil, errors.New("only one of glob or regex can be set")
+	case r.Glob != "":
+		return regexp.MustCompile(globToRegex(r.Glob)), nil
+	case r.Regex != "":
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		return re, nil
+	default:
+		return nil, errors.New("one of glob or regex must be set")
+	}
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(Fields))
+	for _, f := range Fields {
+		names = append(names, string(f))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// Object holds the fields of a collected object, objects only have the fields which apply to them
+type Object map[Field]string
+
+// Values returns the values rules on the field match, the object path comes with the inventory path
+func (o Object) Values(field Field) []string {
+	v, ok := o[field]
+	if !ok || field == InventoryPath {
+		return nil
+	}
+
+	values := []string{v}
+	if p, ok := o[InventoryPath]; ok && field == ObjectPath && p != v {
+		values = append(values, p)
+	}
+
+	return values
+}
+
+// Filter keeps the objects matching the include rules, unless they match an exclude rule.
+// Include rules only apply to objects having their field, an object is kept if it matches
+// at least one include rule of every such field. A nil filter keeps every object.
+type Filter struct {
+	include map[Field][]*regexp.Regexp
+	exclude map[Field][]*regexp.Regexp
+}
+
+func New(include, exclude []Rule) (*Filter, error) {
+	f := &Filter{
+		include: make(map[Field][]*regexp.Regexp),
+		exclude: make(map[Field][]*regexp.Regexp),
+	}
+
+	for i, r := range include {
+		re, err := r.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid include rule %d: %v", i, err)
+		}
+		f.include[r.Field] = append(f.include[r.Field], re)
+	}
+
+	for i, r := range exclude {
+		re, err := r.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude rule %d: %v", i, err)
+		}
+		f.exclude[r.Field] = append(f.exclude[r.Field], re)
+	}
+
+	return f, nil
+}
+
+// Keep reports whether the object passes the filter
+func (f *Filter) Keep(o Object) bool {
+	if f == nil {
+		return true
+	}
+
+	for field := range o {
+		values := o.Values(field)
+		if len(values) == 0 {
+			continue
+		}
+
+		if matchAny(f.exclude[field], values) {
+			return false
+		}
+
+		rules, ok := f.include[field]
+		if ok && !matchAny(rules, values) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAny(rules []*regexp.Regexp, values []string) bool {
+	for _, re := range rules {
+		for _, v := range values {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Apply returns the items passing the filter, object returns the fields of an item
+func Apply[T any](f *Filter, items []T, object func(T) Object) []T {
+	if f == nil {
+		return items
+	}
+
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		if f.Keep(object(item)) {
+			kept = append(kept, item)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,57 @@
+package filter
+
+import "testing"
+
+func TestKeep(t *testing.T) {
+	f, err := New(
+		[]Rule{{Field: Platform, Glob: "vmware"}, {Field: Platform, Glob: "hyperv"}},
+		[]Rule{{Field: JobName, Regex: `^test-`}, {Field: ObjectPath, Glob: `*\lab-*`}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		object Object
+		keep   bool
+	}{
+		{Object{JobName: "Daily VM Backup", Platform: "VMware"}, true},
+		{Object{JobName: "Daily VM Backup", Platform: "HyperV"}, true},
+		{Object{JobName: "File Shares", Platform: "NasBackup"}, false},
+		{Object{JobName: "test-restore", Platform: "VMware"}, false},
+		{Object{ObjectPath: `vcenter.lab.local\Datacenter\web01`, Platform: "VMware"}, true},
+		{Object{ObjectPath: `vcenter.lab.local\Datacenter\LAB-07`, Platform: "VMware"}, false},
+		// object path rules match the inventory path of a vm as well
+		{Object{ObjectPath: `vcenter.lab.local\Datacenter\Lab\web07`, InventoryPath: `vcenter.lab.local\lab-07`, Platform: "VMware"}, false},
+		{Object{ObjectPath: `vcenter.lab.local\Datacenter\Lab\web07`, InventoryPath: `vcenter.lab.local\web07`, Platform: "VMware"}, true},
+		{Object{InventoryPath: `vcenter.lab.local\lab-07`, Platform: "VMware"}, true},
+		// include rules only apply to objects having their field
+		{Object{Repository: "Default Backup Repository"}, true},
+	}
+
+	for _, c := range cases {
+		if got := f.Keep(c.object); got != c.keep {
+			t.Errorf("%v: expected keep %v, got %v", c.object, c.keep, got)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	invalid := []Rule{
+		{Field: "vm_name", Glob: "*"},
+		{Field: JobName},
+		{Field: JobName, Glob: "*", Regex: ".*"},
+		{Field: JobName, Regex: "("},
+	}
+
+	for _, r := range invalid {
+		if _, err := r.Compile(); err == nil {
+			t.Errorf("%+v: expected compile error", r)
+		}
+	}
+
+	var f *Filter
+	if !f.Keep(Object{JobName: "any"}) {
+		t.Error("expected nil filter to keep every object")
+	}
+}
@@ -2,49 +2,247 @@ package influx
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/ZeljkoBenovic/govein/pkg/schema"
+	"github.com/ZeljkoBenovic/govein/pkg/tags"
 	"github.com/ZeljkoBenovic/govein/pkg/veeam"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
-	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 )
 
 type Influx struct {
 	ctx  context.Context
 	log  *slog.Logger
-	cl   influxdb2.Client
+	w    Writer
 	conf config.Config
-	wb   api.WriteAPIBlocking
+
+	tagger *tags.Tagger
+	// schema is nil when the legacy names are kept
+	schema *schema.Schema
 }
 
 func NewInflux(ctx context.Context, conf config.Config, log *slog.Logger) (*Influx, error) {
-	cl := influxdb2.NewClient(conf.Influx.Host, conf.Influx.Token)
+	w, err := NewWriter(conf.Influx)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := cl.Health(ctx)
+	version, err := w.Ping(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("influx db server not healthy: %v", err)
+		return nil, err
 	}
 
 	log.Info("InfluxDB server info",
-		"name", resp.Name,
-		"status", resp.Status,
-		"msg", *resp.Message,
-		"version", *resp.Version,
+		"host", conf.Influx.Host,
+		"version", version,
 	)
 
+	return NewWithWriter(ctx, conf, log, w)
+}
+
+// NewWithWriter returns an Influx writing the points to w, which does not have to be an influxdb server
+func NewWithWriter(ctx context.Context, conf config.Config, log *slog.Logger, w Writer) (*Influx, error) {
+	t, err := tags.New(conf.Veeam.Tags, conf.TagRules)
+	if err != nil {
+		return nil, fmt.Errorf("could not create tagger: %v", err)
+	}
+
+	s, err := newSchema(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Influx{
 		ctx:  ctx,
 		log:  log,
-		cl:   cl,
+		w:    w,
 		conf: conf,
-		wb:   cl.WriteAPIBlocking(conf.Influx.Org, conf.Influx.Bucket),
+
+		tagger: t,
+		schema: s,
 	}, nil
 }
 
+// SetConfig swaps the settings used when storing data, such as the custom tags and the schema.
+// Connection settings need a new client instead.
+func (i *Influx) SetConfig(conf config.Config) error {
+	t, err := tags.New(conf.Veeam.Tags, conf.TagRules)
+	if err != nil {
+		return fmt.Errorf("could not create tagger: %v", err)
+	}
+
+	s, err := newSchema(conf)
+	if err != nil {
+		return err
+	}
+
+	i.conf = conf
+	i.tagger = t
+	i.schema = s
+
+	return nil
+}
+
+func newSchema(conf config.Config) (*schema.Schema, error) {
+	s, err := schema.Load(conf.Schema.Profile, conf.Schema.MappingFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load schema: %v", err)
+	}
+
+	if s.IsIdentity() {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// rename returns the point with the measurement, tag and field names of the schema
+func (i *Influx) rename(p *write.Point) *write.Point {
+	if i.schema == nil {
+		return p
+	}
+
+	r := influxdb2.NewPointWithMeasurement(i.schema.Measurement(p.Name()))
+	for _, t := range p.TagList() {
+		r.AddTag(i.schema.Tag(t.Key), t.Value)
+	}
+
+	for _, f := range p.FieldList() {
+		r.AddField(i.schema.Field(f.Key), f.Value)
+	}
+
+	if !p.Time().IsZero() {
+		r.SetTime(p.Time())
+	}
+
+	// renamed keys are no longer in order
+	return r.SortTags().SortFields()
+}
+
+// write renames the point to the schema, adds the custom tags of the object and writes it.
+// Built-in tags are never overridden.
+func (i *Influx) write(p *write.Point, o filter.Object) error {
+	p = i.rename(p)
+
+	custom := i.tagger.Tags(o)
+
+	keys := make([]string, 0, len(custom))
+	for k := range custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	builtin := make(map[string]struct{}, len(p.TagList()))
+	for _, t := range p.TagList() {
+		builtin[t.Key] = struct{}{}
+	}
+
+	for _, k := range keys {
+		if _, ok := builtin[k]; !ok {
+			p.AddTag(k, custom[k])
+		}
+	}
+
+	return i.w.WritePoints(i.ctx, p)
+}
+
+func (i *Influx) Name() string {
+	return config.SinkInflux
+}
+
+// Store writes everything collected by a cycle
+func (i *Influx) Store(v veeam.Veeam) error {
+	if err := i.SetVeeamServerInfo(v.ServerInfo); err != nil {
+		return err
+	}
+
+	if err := i.SetVeeamSessions(v.Sessions); err != nil {
+		return err
+	}
+
+	if err := i.SetManagedServers(v.ManagedSevers); err != nil {
+		return err
+	}
+
+	if err := i.SetRepositories(v); err != nil {
+		return err
+	}
+
+	if err := i.SetProxies(v.Proxies); err != nil {
+		return err
+	}
+
+	if err := i.SetProxyStates(v); err != nil {
+		return err
+	}
+
+	if err := i.SetWanAccelerators(v); err != nil {
+		return err
+	}
+
+	if err := i.SetProtection(v); err != nil {
+		return err
+	}
+
+	if err := i.SetConfigBackup(v); err != nil {
+		return err
+	}
+
+	if err := i.SetCredentials(v.Credentials); err != nil {
+		return err
+	}
+
+	if err := i.SetCertificate(v); err != nil {
+		return err
+	}
+
+	if err := i.SetBackupObjects(v.BackupObjects); err != nil {
+		return err
+	}
+
+	if err := i.SetUnstructuredDataServers(v.UnstructuredDataServers); err != nil {
+		return err
+	}
+
+	if err := i.SetFileShareJobs(v.FileShareJobs); err != nil {
+		return err
+	}
+
+	if err := i.SetFileShareSessions(v.FileShareSessions); err != nil {
+		return err
+	}
+
+	if err := i.SetCollectors(v.Collectors); err != nil {
+		return err
+	}
+
+	if err := i.SetAPIStats(v.API); err != nil {
+		return err
+	}
+
+	return i.Close()
+}
+
+// StoreStatus stores only the collector states and api client stats, for the cycles failing to collect the rest
+func (i *Influx) StoreStatus(v veeam.Veeam) error {
+	if err := i.SetCollectors(v.Collectors); err != nil {
+		return err
+	}
+
+	if err := i.SetAPIStats(v.API); err != nil {
+		return err
+	}
+
+	return i.Close()
+}
+
 func (i *Influx) SetVeeamServerInfo(info veeam.ServerInfo) error {
 	i.log.Info("Storing veeam server info into database")
 
@@ -56,30 +254,64 @@ func (i *Influx) SetVeeamServerInfo(info veeam.ServerInfo) error {
 		AddTag("veeamDatabaseVendor", info.DatabaseVendor).
 		AddField("vbr", 1)
 
-	return i.wb.WritePoint(i.ctx, p)
+	return i.write(p, nil)
 }
 
 func (i *Influx) SetVeeamSessions(sess veeam.Sessions) error {
 	i.log.Info("Storing sessions into database")
 
+	return i.writeSessions("veeam_vbr_sessions", sess)
+}
+
+// SetTaskSessions writes the task sessions, the session of every object processed by a job session.
+// They are only collected by the backfill.
+func (i *Influx) SetTaskSessions(ts veeam.TaskSessions) error {
+	i.log.Info("Storing task sessions into database")
+
 	result := map[string]int{
 		"Success": 1,
 		"Warning": 2,
 		"Failed":  3,
 	}
 
-	for _, s := range sess.Data {
-		if s.Result.Result == "None" {
-			i.log.Debug("Skipping session with no data", "session_name", s.Name)
+	for _, t := range ts.Data {
+		if t.Result.Result == "None" {
+			i.log.Debug("Skipping task session with no data", "task_name", t.Name)
 			continue
 		}
 
-		if _, ok := i.conf.Veeam.ExcludedJobTypes[s.SessionType]; ok {
-			i.log.Debug("Skipping session with excluded job type", "session_name", s.Name, "session_type", s.SessionType)
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_task_sessions").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRTaskObjectName", t.Name).
+			AddTag("veeamVBRTaskSessiontype", t.SessionType).
+			AddTag("veeamVBRTaskState", t.State).
+			AddTag("veeamVBRTaskResultMessage", t.Result.Message).
+			AddField("veeamVBRTaskResult", result[t.Result.Result]).
+			AddField("veeamVBRTaskDuration", t.EndTime.Sub(t.CreationTime).Seconds()).
+			SetTime(t.EndTime)
+
+		if err := i.write(p, t.FilterObject()); err != nil {
+			return fmt.Errorf("could not write veeam task session: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Influx) writeSessions(measurement string, sess veeam.Sessions) error {
+	result := map[string]int{
+		"Success": 1,
+		"Warning": 2,
+		"Failed":  3,
+	}
+
+	for _, s := range sess.Data {
+		if s.Result.Result == "None" {
+			i.log.Debug("Skipping session with no data", "session_name", s.Name)
 			continue
 		}
 
-		p := influxdb2.NewPointWithMeasurement("veeam_vbr_sessions").
+		p := influxdb2.NewPointWithMeasurement(measurement).
 			AddTag("veeamVBR", i.conf.Veeam.Host).
 			AddTag("veeamVBRSessionJobName", s.Name).
 			AddTag("veeamVBRSessiontype", s.SessionType).
@@ -89,7 +321,7 @@ func (i *Influx) SetVeeamSessions(sess veeam.Sessions) error {
 			AddField("veeamBackupSessionsTimeDuration", s.EndTime.Sub(s.CreationTime).Seconds()).
 			SetTime(s.EndTime)
 
-		if err := i.wb.WritePoint(i.ctx, p); err != nil {
+		if err := i.write(p, s.FilterObject()); err != nil {
 			return fmt.Errorf("could not write veeam session: %v", err)
 		}
 	}
@@ -108,7 +340,7 @@ func (i *Influx) SetManagedServers(srv veeam.ManagedSevers) error {
 			AddTag("veeamVBRMSDescription", s.Description).
 			AddField("veeamVBRMSInternalID", ind)
 
-		if err := i.wb.WritePoint(i.ctx, p); err != nil {
+		if err := i.write(p, nil); err != nil {
 			return fmt.Errorf("could not write veeam managed servers: %v", err)
 		}
 	}
@@ -145,13 +377,9 @@ func (i *Influx) SetRepositories(v veeam.Veeam) error {
 						i.log.Error("Unknown repository type", "type", rd.Type)
 					}
 
-					if err := i.wb.WritePoint(i.ctx, p); err != nil {
+					if err := i.write(p, a.FilterObject()); err != nil {
 						return fmt.Errorf("could not write veeam repositories: %v", err)
 					}
-
-					if err := i.wb.Flush(i.ctx); err != nil {
-						return err
-					}
 				}
 			}
 		}
@@ -172,7 +400,7 @@ func (i *Influx) SetProxies(pr veeam.Proxies) error {
 			AddTag("veeamVBRProxyMode", p.Server.TransportMode).
 			AddField("veeamVBRProxyTask", p.Server.MaxTaskCount)
 
-		if err := i.wb.WritePoint(i.ctx, data); err != nil {
+		if err := i.write(data, p.FilterObject()); err != nil {
 			return fmt.Errorf("could not write veeam proxies: %v", err)
 		}
 	}
@@ -180,6 +408,86 @@ func (i *Influx) SetProxies(pr veeam.Proxies) error {
 	return nil
 }
 
+func (i *Influx) SetProxyStates(v veeam.Veeam) error {
+	i.log.Info("Storing proxy states into database")
+
+	// the proxies would be stored as offline
+	if v.Skipped(veeam.CollectorProxyStates) {
+		i.log.Warn("Proxy states were not collected, skipping them")
+		return nil
+	}
+
+	boolToString := map[bool]string{
+		true:  "true",
+		false: "false",
+	}
+
+	for _, p := range v.Proxies.Data {
+		hostName := p.Server.HostID
+		if host, ok := v.ManagedSevers.ServerByID(p.Server.HostID); ok {
+			hostName = host.Name
+		}
+
+		var state veeam.ProxyStatesData
+		for _, s := range v.ProxyStates.Data {
+			if s.ID == p.ID {
+				state = s
+				break
+			}
+		}
+
+		data := influxdb2.NewPointWithMeasurement("veeam_vbr_proxy_states").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRProxyName", p.Name).
+			AddTag("veeamVBRProxyType", p.Type).
+			AddTag("veeamVBRProxyHost", hostName).
+			AddTag("veeamVBRProxyDisabled", boolToString[state.IsDisabled]).
+			AddTag("veeamVBRProxyOutOfDate", boolToString[state.IsOutOfDate]).
+			AddField("veeamVBRProxyOnline", boolToInt(state.IsOnline)).
+			AddField("veeamVBRProxyTask", p.Server.MaxTaskCount)
+
+		if err := i.write(data, p.FilterObject()); err != nil {
+			return fmt.Errorf("could not write veeam proxy states: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Influx) SetWanAccelerators(v veeam.Veeam) error {
+	i.log.Info("Storing wan accelerators into database")
+
+	boolToString := map[bool]string{
+		true:  "true",
+		false: "false",
+	}
+
+	for _, w := range v.WanAccelerators.Data {
+		hostName := w.Server.HostID
+		online := false
+		if host, ok := v.ManagedSevers.ServerByID(w.Server.HostID); ok {
+			hostName = host.Name
+			online = host.IsAvailable()
+		}
+
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_wan_accelerators").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRWanName", w.Name).
+			AddTag("veeamVBRWanDescription", w.Description).
+			AddTag("veeamVBRWanHost", hostName).
+			AddTag("veeamVBRWanHighBandwidth", boolToString[w.Server.HighBandwidthModeEnabled]).
+			AddField("veeamVBRWanOnline", boolToInt(online)).
+			AddField("veeamVBRWanStreams", w.Server.StreamsCount).
+			AddField("veeamVBRWanCacheSize", w.Cache.CacheSizeBytes())
+
+		if err := i.write(p, nil); err != nil {
+			return fmt.Errorf("could not write veeam wan accelerators: %v", err)
+		}
+	}
+
+	return nil
+}
+
 func (i *Influx) SetBackupObjects(bo veeam.BackupObjects) error {
 	i.log.Info("Storing backup objects into database")
 
@@ -190,11 +498,12 @@ func (i *Influx) SetBackupObjects(bo veeam.BackupObjects) error {
 			AddTag("veeamVBRBobjecttype", string(b.Type)).
 			AddTag("veeamVBRBobjectPlatform", string(b.PlatformName)).
 			AddTag("veeamVBRBobjectviType", string(b.ViType)).
+			AddTag("veeamVBRBobjectKind", string(b.Kind())).
 			AddTag("veeamVBRBobjectObjectId", b.ObjectID).
 			AddTag("veeamVBRBobjectPath", b.Path).
 			AddField("restorePointsCount", b.RestorePointsCount)
 
-		if err := i.wb.WritePoint(i.ctx, p); err != nil {
+		if err := i.write(p, b.FilterObject()); err != nil {
 			return fmt.Errorf("could not write veeam backup objects: %v", err)
 		}
 	}
@@ -202,24 +511,275 @@ func (i *Influx) SetBackupObjects(bo veeam.BackupObjects) error {
 	return nil
 }
 
-func (i *Influx) FlushAndClose() error {
-	if err := i.wb.Flush(i.ctx); err != nil {
-		return err
+func (i *Influx) SetUnstructuredDataServers(uds veeam.UnstructuredDataServers) error {
+	i.log.Info("Storing unstructured data servers into database")
+
+	boolToString := map[bool]string{
+		true:  "true",
+		false: "false",
 	}
-	i.cl.Close()
+
+	for _, u := range uds.Data {
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_unstructured_servers").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRUDSName", u.DisplayName()).
+			AddTag("veeamVBRUDSType", string(u.Type)).
+			AddTag("veeamVBRUDSObjectStorage", boolToString[u.Type.IsObjectStorage()]).
+			AddTag("veeamVBRUDSProxyAutoSelect", boolToString[u.Processing.BackupProxies.AutoSelectEnabled]).
+			AddField("veeamVBRUDSProxies", len(u.Processing.BackupProxies.ProxyIDs))
+
+		if err := i.write(p, u.FilterObject()); err != nil {
+			return fmt.Errorf("could not write veeam unstructured data servers: %v", err)
+		}
+	}
+
 	return nil
 }
 
-func (i *Influx) Ping() error {
-	i.log.Info("Pinging influxdb server")
+func (i *Influx) SetFileShareJobs(jobs veeam.FileShareJobs) error {
+	i.log.Info("Storing file share jobs into database")
 
-	isRunning, err := i.cl.Ping(i.ctx)
-	if err != nil {
-		return err
+	boolToString := map[bool]string{
+		true:  "true",
+		false: "false",
+	}
+
+	for _, j := range jobs.Data {
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_fileshare_jobs").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRFSJobName", j.Name).
+			AddTag("veeamVBRFSJobType", j.Type).
+			AddTag("veeamVBRFSJobDescription", j.Description).
+			AddTag("veeamVBRFSJobDisabled", boolToString[j.IsDisabled]).
+			AddField("veeamVBRFSJobObjects", len(j.Objects))
+
+		if err := i.write(p, j.FilterObject()); err != nil {
+			return fmt.Errorf("could not write veeam file share jobs: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Influx) SetFileShareSessions(sess veeam.Sessions) error {
+	i.log.Info("Storing file share sessions into database")
+
+	return i.writeSessions("veeam_vbr_fileshare_sessions", sess)
+}
+
+func (i *Influx) Close() error {
+	return i.w.Close()
+}
+
+func (i *Influx) SetConfigBackup(v veeam.Veeam) error {
+	i.log.Info("Storing configuration backup into database")
+
+	// the configuration backup would be stored as never succeeded
+	if v.Skipped(veeam.CollectorConfigBackup) {
+		i.log.Warn("Configuration backup was not collected, skipping it")
+		return nil
+	}
+
+	boolToString := map[bool]string{
+		true:  "true",
+		false: "false",
+	}
+
+	result := map[string]int{
+		"Success": 1,
+		"Warning": 2,
+		"Failed":  3,
+	}
+
+	cb := v.ConfigBackup
+
+	repoName := cb.BackupRepositoryID
+	if repo, ok := v.AllRepositories.RepositoryByID(cb.BackupRepositoryID); ok {
+		repoName = repo.Name
+	}
+
+	lastResult := "None"
+	if cb.LastSession != nil {
+		lastResult = cb.LastSession.Result.Result
 	}
-	if !isRunning {
-		return errors.New("influxdb server ping failed")
+
+	p := influxdb2.NewPointWithMeasurement("veeam_vbr_config_backup").
+		AddTag("veeamVBR", i.conf.Veeam.Host).
+		AddTag("veeamVBRConfigBackupEnabled", boolToString[cb.IsEnabled]).
+		AddTag("veeamVBRConfigBackupEncrypted", boolToString[cb.Encryption.IsEnabled]).
+		AddTag("veeamVBRConfigBackupRepository", repoName).
+		AddTag("veeamVBRConfigBackupLastResult", lastResult).
+		AddField("veeamVBRConfigBackupResult", result[lastResult]).
+		AddField("veeamVBRConfigBackupRestorePoints", cb.RestorePointsToKeep)
+
+	if age, ok := cb.LastSuccessAge(time.Now()); ok {
+		p.AddField("veeamVBRConfigBackupLastSuccessAge", age.Seconds())
+		p.AddField("veeamVBRConfigBackupHasSuccess", 1)
+	} else {
+		p.AddField("veeamVBRConfigBackupHasSuccess", 0)
+	}
+
+	if err := i.write(p, nil); err != nil {
+		return fmt.Errorf("could not write veeam configuration backup: %v", err)
 	}
 
 	return nil
 }
+
+func (i *Influx) SetProtection(v veeam.Veeam) error {
+	i.log.Info("Storing protection coverage into database")
+
+	// every object would be stored as unprotected, or none of them
+	if v.Skipped(veeam.CollectorInventory, veeam.CollectorJobs, veeam.CollectorJobContainers, veeam.CollectorBackupObjects) {
+		i.log.Warn("Protection data was not collected, skipping the protection coverage")
+		return nil
+	}
+
+	report := v.Protection()
+
+	for _, o := range report.Unprotected {
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_unprotected_objects").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRUnprotectedName", o.Name).
+			AddTag("veeamVBRUnprotectedHost", o.HostName).
+			AddTag("veeamVBRUnprotectedObjectId", o.ObjectID).
+			AddTag("veeamVBRUnprotectedPlatform", string(o.Platform)).
+			AddField("veeamVBRUnprotected", 1)
+
+		if err := i.write(p, o.FilterObject()); err != nil {
+			return fmt.Errorf("could not write veeam unprotected objects: %v", err)
+		}
+	}
+
+	p := influxdb2.NewPointWithMeasurement("veeam_vbr_protection_coverage").
+		AddTag("veeamVBR", i.conf.Veeam.Host).
+		AddField("veeamVBRProtectionTotal", report.Total).
+		AddField("veeamVBRProtectionProtected", report.Protected).
+		AddField("veeamVBRProtectionUnprotected", len(report.Unprotected)).
+		AddField("veeamVBRProtectionCoverage", report.Coverage())
+
+	if err := i.write(p, nil); err != nil {
+		return fmt.Errorf("could not write veeam protection coverage: %v", err)
+	}
+
+	return nil
+}
+
+// SetCredentials stores the credentials records with their age since creation,
+// the api does not report when a credentials record was last changed
+func (i *Influx) SetCredentials(creds veeam.Credentials) error {
+	i.log.Info("Storing credentials into database")
+
+	now := time.Now()
+
+	for _, c := range creds.Data {
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_credentials").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRCredsId", c.ID).
+			AddTag("veeamVBRCredsUsername", c.Username).
+			AddTag("veeamVBRCredsType", c.Type).
+			AddTag("veeamVBRCredsDescription", c.Description).
+			AddField("veeamVBRCredsCreatedAge", now.Sub(c.CreationTime).Seconds())
+
+		if err := i.write(p, nil); err != nil {
+			return fmt.Errorf("could not write veeam credentials: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Influx) SetCertificate(v veeam.Veeam) error {
+	i.log.Info("Storing server certificate into database")
+
+	// the certificate would be stored as expired
+	if v.Skipped(veeam.CollectorCertificate) {
+		i.log.Warn("Server certificate was not collected, skipping it")
+		return nil
+	}
+
+	cert := v.Certificate
+
+	now := time.Now()
+	expiresIn := cert.ExpiresIn(now)
+
+	p := influxdb2.NewPointWithMeasurement("veeam_vbr_certificate").
+		AddTag("veeamVBR", i.conf.Veeam.Host).
+		AddTag("veeamVBRCertSubject", cert.Subject).
+		AddTag("veeamVBRCertIssuer", cert.IssuedBy).
+		AddTag("veeamVBRCertSerial", cert.SerialNumber).
+		AddTag("veeamVBRCertThumbprint", cert.Thumbprint).
+		AddField("veeamVBRCertExpiresIn", expiresIn.Seconds()).
+		AddField("veeamVBRCertDaysLeft", int64(expiresIn.Hours()/24)).
+		AddField("veeamVBRCertAge", now.Sub(cert.ValidFrom).Seconds())
+
+	if err := i.write(p, nil); err != nil {
+		return fmt.Errorf("could not write veeam server certificate: %v", err)
+	}
+
+	return nil
+}
+
+// SetCollectors stores the state of every collector, 0 ok, 1 skipped, 2 failed
+func (i *Influx) SetCollectors(collectors []veeam.CollectorStatus) error {
+	i.log.Info("Storing collector states into database")
+
+	for _, c := range collectors {
+		var msg string
+		if c.Err != nil {
+			msg = c.Err.Error()
+		}
+
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_collectors").
+			AddTag("veeamVBR", i.conf.Veeam.Host).
+			AddTag("veeamVBRCollector", c.Name).
+			AddField("veeamVBRCollectorState", int(c.State)).
+			AddField("veeamVBRCollectorError", msg)
+
+		if err := i.write(p, nil); err != nil {
+			return fmt.Errorf("could not write veeam collector states: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// SetAPIStats stores the counters of the veeam api client, they only grow until govein restarts
+func (i *Influx) SetAPIStats(stats veeam.APIStats) error {
+	i.log.Info("Storing veeam api client stats into database")
+
+	p := influxdb2.NewPointWithMeasurement("veeam_vbr_api").
+		AddTag("veeamVBR", i.conf.Veeam.Host).
+		AddField("veeamVBRApiRequests", stats.Requests).
+		AddField("veeamVBRApiRetries", stats.Retries).
+		AddField("veeamVBRApiRateLimited", stats.RateLimited).
+		AddField("veeamVBRApiFailures", stats.Failures).
+		AddField("veeamVBRApiBreakerState", int(stats.BreakerState)).
+		AddField("veeamVBRApiBreakerTrips", stats.BreakerTrips).
+		AddField("veeamVBRApiAuthErrors", stats.AuthErrors).
+		AddField("veeamVBRApiPermissionErrors", stats.PermissionErrors).
+		AddField("veeamVBRApiNotFoundErrors", stats.NotFoundErrors).
+		AddField("veeamVBRApiServerErrors", stats.ServerErrors)
+
+	if err := i.write(p, nil); err != nil {
+		return fmt.Errorf("could not write veeam api client stats: %v", err)
+	}
+
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func (i *Influx) Ping() error {
+	i.log.Info("Pinging influxdb server")
+
+	_, err := i.w.Ping(i.ctx)
+
+	return err
+}
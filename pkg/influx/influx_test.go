@@ -0,0 +1,124 @@
+package influx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/ZeljkoBenovic/govein/pkg/schema"
+	"github.com/ZeljkoBenovic/govein/pkg/tags"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// fakeInflux records the line protocol written to it
+type fakeInflux struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (f *fakeInflux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/health":
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"influxdb","message":"ready for queries and writes","status":"pass","version":"v2.7.0"}`))
+	case "/api/v2/write":
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.lines = append(f.lines, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestInflux(t *testing.T, conf config.Config) (*Influx, *fakeInflux) {
+	t.Helper()
+
+	fi := &fakeInflux{}
+	srv := httptest.NewServer(fi)
+	t.Cleanup(srv.Close)
+
+	conf.Influx = config.Influx{Host: srv.URL, Token: "token", Org: "govein", Bucket: "veeam"}
+	if conf.Veeam.Host == "" {
+		conf.Veeam.Host = "https://vbr.lab.local:9419"
+	}
+
+	i, err := NewInflux(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create influx client: %v", err)
+	}
+
+	return i, fi
+}
+
+func testSessions() veeam.Sessions {
+	var sess veeam.Sessions
+	for _, name := range []string{"acme-daily", "globex-daily"} {
+		s := veeam.SessionsData{Name: name, SessionType: "BackupJob", State: "Stopped"}
+		s.Result.Result = "Success"
+		sess.Data = append(sess.Data, s)
+	}
+
+	return sess
+}
+
+func TestCustomTags(t *testing.T) {
+	var conf config.Config
+	conf.Veeam.Tags = map[string]string{"site": "ams", "veeamVBR": "overridden"}
+	conf.TagRules = []tags.Rule{{Field: filter.JobName, Glob: "acme-*", Tags: map[string]string{"tenant": "acme"}}}
+
+	i, fi := newTestInflux(t, conf)
+
+	if err := i.SetVeeamSessions(testSessions()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fi.lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", fi.lines)
+	}
+
+	for _, l := range fi.lines {
+		if !strings.Contains(l, "site=ams") || !strings.Contains(l, `veeamVBR=https://vbr.lab.local:9419`) {
+			t.Errorf("expected static tags without overriding built-in ones, got %q", l)
+		}
+	}
+
+	if !strings.Contains(fi.lines[0], "tenant=acme") || strings.Contains(fi.lines[1], "tenant=") {
+		t.Errorf("expected tenant tag on acme sessions only, got %v", fi.lines)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	mapping := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(mapping, []byte("measurements:\n  veeam_vbr_sessions: backup_sessions\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf config.Config
+	conf.Schema = config.Schema{Profile: schema.SnakeCase, MappingFile: mapping}
+	// custom tags never override renamed built-in tags
+	conf.Veeam.Tags = map[string]string{"job_name": "overridden"}
+
+	i, fi := newTestInflux(t, conf)
+
+	if err := i.SetVeeamSessions(testSessions()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "backup_sessions,job_name=acme-daily,session_type=BackupJob,state=Stopped,vbr_server=https://vbr.lab.local:9419 duration_seconds=0,result=1i"
+	if len(fi.lines) != 2 || fi.lines[0] != want {
+		t.Errorf("expected %q, got %v", want, fi.lines)
+	}
+}
@@ -0,0 +1,222 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	lp "github.com/influxdata/line-protocol"
+)
+
+const (
+	V1 = 1
+	V2 = 2
+	V3 = 3
+
+	httpTimeout = 20 * time.Second
+)
+
+// Writer writes points to an influxdb server, each version has its own auth and target model
+type Writer interface {
+	// Ping checks the server is up and returns its version, if it reports one
+	Ping(ctx context.Context) (string, error)
+	WritePoints(ctx context.Context, points ...*write.Point) error
+	// Close releases the idle connections, the writer can still be used afterwards
+	Close() error
+}
+
+// NewWriter returns the writer of the configured influxdb version, InfluxDB 2 is the default
+func NewWriter(conf config.Influx) (Writer, error) {
+	switch conf.Version {
+	case V1:
+		return newV1Writer(conf)
+	case V2, 0:
+		return newV2Writer(conf), nil
+	case V3:
+		return newV3Writer(conf)
+	default:
+		return nil, fmt.Errorf("unsupported influxdb version %d", conf.Version)
+	}
+}
+
+// v2Writer writes to the InfluxDB 2 api with a token, into a bucket of an org
+type v2Writer struct {
+	cl influxdb2.Client
+	wb api.WriteAPIBlocking
+}
+
+func newV2Writer(conf config.Influx) *v2Writer {
+	cl := influxdb2.NewClient(conf.Host, conf.Token)
+
+	return &v2Writer{
+		cl: cl,
+		wb: cl.WriteAPIBlocking(conf.Org, conf.Bucket),
+	}
+}
+
+func (w *v2Writer) Ping(ctx context.Context) (string, error) {
+	resp, err := w.cl.Health(ctx)
+	if err != nil {
+		return "", fmt.Errorf("influx db server not healthy: %v", err)
+	}
+
+	if resp.Version == nil {
+		return "", nil
+	}
+
+	return *resp.Version, nil
+}
+
+func (w *v2Writer) WritePoints(ctx context.Context, points ...*write.Point) error {
+	return w.wb.WritePoint(ctx, points...)
+}
+
+func (w *v2Writer) Close() error {
+	if err := w.wb.Flush(context.Background()); err != nil {
+		return err
+	}
+
+	w.cl.Close()
+
+	return nil
+}
+
+// httpWriter posts line protocol to the write endpoints of InfluxDB 1.x and 3,
+// which have no client in influxdb-client-go
+type httpWriter struct {
+	cl       *http.Client
+	pingURL  string
+	writeURL string
+	// auth sets the credentials of the version on a request
+	auth func(r *http.Request)
+}
+
+// newV1Writer writes to the InfluxDB 1.x api with an optional username and password,
+// into a database and an optional retention policy
+func newV1Writer(conf config.Influx) (*httpWriter, error) {
+	q := url.Values{"db": {conf.Database}, "precision": {"ns"}}
+	if conf.RetentionPolicy != "" {
+		q.Set("rp", conf.RetentionPolicy)
+	}
+
+	return newHTTPWriter(conf.Host, "/write", q, func(r *http.Request) {
+		if conf.Username != "" {
+			r.SetBasicAuth(conf.Username, conf.Password)
+		}
+	})
+}
+
+// newV3Writer writes to the InfluxDB 3 api with a token, into a database
+func newV3Writer(conf config.Influx) (*httpWriter, error) {
+	q := url.Values{"db": {conf.Database}, "precision": {"nanosecond"}}
+
+	return newHTTPWriter(conf.Host, "/api/v3/write_lp", q, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+conf.Token)
+	})
+}
+
+func newHTTPWriter(host, writePath string, q url.Values, auth func(r *http.Request)) (*httpWriter, error) {
+	u, err := url.Parse(strings.TrimSuffix(host, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse influx host: %v", err)
+	}
+
+	writeURL := *u
+	writeURL.Path += writePath
+	writeURL.RawQuery = q.Encode()
+
+	return &httpWriter{
+		cl:       &http.Client{Timeout: httpTimeout},
+		pingURL:  u.String() + "/ping",
+		writeURL: writeURL.String(),
+		auth:     auth,
+	}, nil
+}
+
+func (w *httpWriter) Ping(ctx context.Context) (string, error) {
+	resp, err := w.do(ctx, http.MethodGet, w.pingURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("influx db server not healthy: %v", err)
+	}
+
+	return resp.Header.Get("X-Influxdb-Version"), nil
+}
+
+func (w *httpWriter) WritePoints(ctx context.Context, points ...*write.Point) error {
+	body, err := Encode(points...)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.do(ctx, http.MethodPost, w.writeURL, bytes.NewReader(body)); err != nil {
+		return fmt.Errorf("could not write points: %v", err)
+	}
+
+	return nil
+}
+
+// Encode returns the line protocol of the points with nanosecond timestamps, the same way the InfluxDB 2 client does
+func Encode(points ...*write.Point) ([]byte, error) {
+	var buf bytes.Buffer
+
+	e := lp.NewEncoder(&buf)
+	e.SetFieldTypeSupport(lp.UintSupport)
+	e.FailOnFieldErr(true)
+	e.SetPrecision(time.Nanosecond)
+
+	for _, p := range points {
+		if _, err := e.Encode(p); err != nil {
+			return nil, fmt.Errorf("could not encode point %s: %v", p.Name(), err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (w *httpWriter) Close() error {
+	w.cl.CloseIdleConnections()
+
+	return nil
+}
+
+// do sends the request and drains the response, non 2xx statuses are returned as errors
+func (w *httpWriter) do(ctx context.Context, method, u string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	w.auth(req)
+
+	resp, err := w.cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(msg) == 0 {
+			return nil, errors.New(resp.Status)
+		}
+
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,109 @@
+package influx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+func TestWriters(t *testing.T) {
+	cases := []struct {
+		conf      config.Influx
+		path      string
+		query     string
+		checkAuth func(r *http.Request) bool
+	}{
+		{
+			conf:  config.Influx{Version: V1, Username: "govein", Password: "secret", Database: "veeam", RetentionPolicy: "autogen"},
+			path:  "/write",
+			query: "db=veeam&precision=ns&rp=autogen",
+			checkAuth: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "govein" && p == "secret"
+			},
+		},
+		{
+			conf:  config.Influx{Version: V3, Token: "token", Database: "veeam"},
+			path:  "/api/v3/write_lp",
+			query: "db=veeam&precision=nanosecond",
+			checkAuth: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer token"
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var body string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !c.checkAuth(r) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch r.URL.Path {
+			case "/ping":
+				w.Header().Set("X-Influxdb-Version", "test")
+				w.WriteHeader(http.StatusNoContent)
+			case c.path:
+				if r.URL.RawQuery != c.query {
+					http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+					return
+				}
+
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		c.conf.Host = srv.URL
+
+		w, err := NewWriter(c.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := w.Ping(context.Background()); err != nil || v != "test" {
+			t.Errorf("version %d: expected ping to return the version, got %q, %v", c.conf.Version, v, err)
+		}
+
+		p := influxdb2.NewPointWithMeasurement("veeam_vbr_info").AddTag("veeamVBR", "vbr").AddField("vbr", 1)
+		if err = w.WritePoints(context.Background(), p); err != nil {
+			t.Errorf("version %d: %v", c.conf.Version, err)
+		}
+
+		if want := "veeam_vbr_info,veeamVBR=vbr vbr=1i\n"; body != want {
+			t.Errorf("version %d: expected %q, got %q", c.conf.Version, want, body)
+		}
+
+		_ = w.Close()
+		srv.Close()
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database not found: \"veeam\""}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(config.Influx{Version: V1, Host: srv.URL, Database: "veeam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := influxdb2.NewPointWithMeasurement("veeam_vbr_info").AddField("vbr", 1)
+	if err = w.WritePoints(context.Background(), p); err == nil {
+		t.Error("expected write error")
+	} else if want := `could not write points: 404 Not Found: {"error":"database not found: \"veeam\""}`; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err)
+	}
+}
@@ -0,0 +1,301 @@
+package otlp
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type instruments struct {
+	info           metric.Int64ObservableGauge
+	sessions       metric.Int64ObservableUpDownCounter
+	jobResult      metric.Int64ObservableGauge
+	jobDuration    metric.Float64ObservableGauge
+	managedServers metric.Int64ObservableUpDownCounter
+	repoCapacity   metric.Float64ObservableGauge
+	repoFree       metric.Float64ObservableGauge
+	repoUsed       metric.Float64ObservableGauge
+	proxyMaxTasks  metric.Int64ObservableGauge
+	proxyOnline    metric.Int64ObservableGauge
+	restorePoints  metric.Int64ObservableUpDownCounter
+	apiRequests    metric.Int64ObservableCounter
+	apiRetries     metric.Int64ObservableCounter
+	apiRateLimited metric.Int64ObservableCounter
+	apiFailures    metric.Int64ObservableCounter
+	apiTrips       metric.Int64ObservableCounter
+	apiErrors      metric.Int64ObservableCounter
+	apiBreaker     metric.Int64ObservableGauge
+	collectorState metric.Int64ObservableGauge
+}
+
+// register creates the instruments, which observe the last stored data every time the reader collects
+func (o *OTLP) register(m metric.Meter) error {
+	var (
+		in  instruments
+		err error
+	)
+
+	gauges := []struct {
+		i    *metric.Int64ObservableGauge
+		name string
+		desc string
+		unit string
+	}{
+		{&in.info, "veeam.vbr.info", "Veeam backup server info, always 1", "1"},
+		{&in.jobResult, "veeam.vbr.job.last_result", "Result of the last session of the job, 1 success, 2 warning, 3 failed", "1"},
+		{&in.proxyMaxTasks, "veeam.vbr.proxy.tasks.max", "Max concurrent tasks of the proxy", "{task}"},
+		{&in.proxyOnline, "veeam.vbr.proxy.online", "Whether the proxy is online", "1"},
+		{&in.apiBreaker, "veeam.vbr.api.breaker.state", "State of the api circuit breaker, 0 closed, 1 half-open, 2 open", "1"},
+		{&in.collectorState, "veeam.vbr.collector.state", "State of the collector in the last collection, 0 ok, 1 skipped, 2 failed", "1"},
+	}
+
+	for _, g := range gauges {
+		if *g.i, err = m.Int64ObservableGauge(g.name, metric.WithDescription(g.desc), metric.WithUnit(g.unit)); err != nil {
+			return err
+		}
+	}
+
+	floatGauges := []struct {
+		i    *metric.Float64ObservableGauge
+		name string
+		desc string
+		unit string
+	}{
+		{&in.jobDuration, "veeam.vbr.job.last_duration", "Duration of the last session of the job", "s"},
+		{&in.repoCapacity, "veeam.vbr.repository.capacity", "Capacity of the repository", "By"},
+		{&in.repoFree, "veeam.vbr.repository.free", "Free space of the repository", "By"},
+		{&in.repoUsed, "veeam.vbr.repository.used", "Used space of the repository", "By"},
+	}
+
+	for _, g := range floatGauges {
+		if *g.i, err = m.Float64ObservableGauge(g.name, metric.WithDescription(g.desc), metric.WithUnit(g.unit)); err != nil {
+			return err
+		}
+	}
+
+	sums := []struct {
+		i    *metric.Int64ObservableUpDownCounter
+		name string
+		desc string
+		unit string
+	}{
+		{&in.sessions, "veeam.vbr.sessions", "Sessions by type and result", "{session}"},
+		{&in.managedServers, "veeam.vbr.managed_servers", "Managed servers by type", "{server}"},
+		{&in.restorePoints, "veeam.vbr.backup_object.restore_points", "Restore points of the backup object", "{restore_point}"},
+	}
+
+	for _, s := range sums {
+		if *s.i, err = m.Int64ObservableUpDownCounter(s.name, metric.WithDescription(s.desc), metric.WithUnit(s.unit)); err != nil {
+			return err
+		}
+	}
+
+	counters := []struct {
+		i    *metric.Int64ObservableCounter
+		name string
+		desc string
+		unit string
+	}{
+		{&in.apiRequests, "veeam.vbr.api.requests", "Requests sent to the veeam api, retries included", "{request}"},
+		{&in.apiRetries, "veeam.vbr.api.retries", "Retried veeam api requests", "{request}"},
+		{&in.apiRateLimited, "veeam.vbr.api.rate_limited", "Rate limited veeam api responses", "{response}"},
+		{&in.apiFailures, "veeam.vbr.api.failures", "Veeam api requests failed after the last retry", "{request}"},
+		{&in.apiTrips, "veeam.vbr.api.breaker.trips", "Times the api circuit breaker opened", "{trip}"},
+		{&in.apiErrors, "veeam.vbr.api.errors", "Veeam api error responses by kind, auth, permission, not_found or server", "{response}"},
+	}
+
+	for _, c := range counters {
+		if *c.i, err = m.Int64ObservableCounter(c.name, metric.WithDescription(c.desc), metric.WithUnit(c.unit)); err != nil {
+			return err
+		}
+	}
+
+	_, err = m.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		o.observe(obs, in)
+		return nil
+	},
+		in.info, in.sessions, in.jobResult, in.jobDuration, in.managedServers,
+		in.repoCapacity, in.repoFree, in.repoUsed,
+		in.proxyMaxTasks, in.proxyOnline, in.restorePoints,
+		in.apiRequests, in.apiRetries, in.apiRateLimited, in.apiFailures, in.apiTrips, in.apiErrors, in.apiBreaker,
+		in.collectorState,
+	)
+
+	return err
+}
+
+// observe records the last stored data.
+// Every attribute set is observed once per collection, so sessions are reduced to the last one of each job.
+func (o *OTLP) observe(obs metric.Observer, in instruments) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	v := o.data
+
+	info := v.ServerInfo
+	obs.ObserveInt64(in.info, 1, o.attrs(nil,
+		attribute.String("veeam.vbr.id", info.VbrId),
+		attribute.String("veeam.vbr.name", info.Name),
+		attribute.String("veeam.vbr.version", info.BuildVersion),
+		attribute.String("veeam.vbr.database_vendor", info.DatabaseVendor),
+	))
+
+	result := map[string]int64{
+		"Success": 1,
+		"Warning": 2,
+		"Failed":  3,
+	}
+
+	type sessionKey struct{ sessionType, result string }
+	type jobKey struct{ name, sessionType string }
+
+	sessionCounts := make(map[sessionKey]int64)
+	last := make(map[jobKey]int)
+
+	for i, s := range v.Sessions.Data {
+		if s.Result.Result == "None" {
+			continue
+		}
+
+		sessionCounts[sessionKey{s.SessionType, s.Result.Result}]++
+
+		k := jobKey{s.Name, s.SessionType}
+		if j, ok := last[k]; !ok || s.EndTime.After(v.Sessions.Data[j].EndTime) {
+			last[k] = i
+		}
+	}
+
+	for k, n := range sessionCounts {
+		obs.ObserveInt64(in.sessions, n, o.attrs(nil,
+			attribute.String("veeam.session.type", k.sessionType),
+			attribute.String("veeam.session.result", k.result),
+		))
+	}
+
+	for _, i := range last {
+		s := v.Sessions.Data[i]
+		opt := o.attrs(s.FilterObject(),
+			attribute.String("veeam.job.name", s.Name),
+			attribute.String("veeam.session.type", s.SessionType),
+		)
+
+		obs.ObserveInt64(in.jobResult, result[s.Result.Result], opt)
+		obs.ObserveFloat64(in.jobDuration, s.EndTime.Sub(s.CreationTime).Seconds(), opt)
+	}
+
+	serverCounts := make(map[string]int64)
+	for _, s := range v.ManagedSevers.Data {
+		serverCounts[s.Type]++
+	}
+
+	for t, n := range serverCounts {
+		obs.ObserveInt64(in.managedServers, n, o.attrs(nil, attribute.String("veeam.server.type", t)))
+	}
+
+	for _, r := range v.Repositories {
+		for _, rd := range r.Data {
+			a, ok := v.AllRepositories.RepositoryByID(rd.ID)
+			if !ok {
+				continue
+			}
+
+			opt := o.attrs(a.FilterObject(),
+				attribute.String("veeam.repository.name", rd.Name),
+				attribute.String("veeam.repository.type", rd.Type),
+			)
+
+			obs.ObserveFloat64(in.repoCapacity, rd.CapacityGB*1024*1024*1024, opt)
+			obs.ObserveFloat64(in.repoFree, rd.FreeGB*1024*1024*1024, opt)
+			obs.ObserveFloat64(in.repoUsed, rd.UsedSpaceGB*1024*1024*1024, opt)
+		}
+	}
+
+	for _, p := range v.Proxies.Data {
+		hostName := p.Server.HostID
+		if host, ok := v.ManagedSevers.ServerByID(p.Server.HostID); ok {
+			hostName = host.Name
+		}
+
+		var online int64
+		for _, s := range v.ProxyStates.Data {
+			if s.ID == p.ID {
+				if s.IsOnline {
+					online = 1
+				}
+				break
+			}
+		}
+
+		opt := o.attrs(p.FilterObject(),
+			attribute.String("veeam.proxy.name", p.Name),
+			attribute.String("veeam.proxy.type", p.Type),
+			attribute.String("veeam.proxy.host", hostName),
+		)
+
+		obs.ObserveInt64(in.proxyMaxTasks, p.Server.MaxTaskCount, opt)
+
+		// the proxies would be observed as offline
+		if !v.Skipped(veeam.CollectorProxyStates) {
+			obs.ObserveInt64(in.proxyOnline, online, opt)
+		}
+	}
+
+	for _, b := range v.BackupObjects.Data {
+		obs.ObserveInt64(in.restorePoints, b.RestorePointsCount, o.attrs(b.FilterObject(),
+			attribute.String("veeam.object.id", b.ObjectID),
+			attribute.String("veeam.object.name", b.Name),
+			attribute.String("veeam.object.type", string(b.Type)),
+			attribute.String("veeam.object.platform", string(b.PlatformName)),
+			attribute.String("veeam.object.path", b.Path),
+		))
+	}
+
+	for _, c := range v.Collectors {
+		obs.ObserveInt64(in.collectorState, int64(c.State), o.attrs(nil, attribute.String("veeam.collector", c.Name)))
+	}
+
+	api := o.attrs(nil)
+	obs.ObserveInt64(in.apiRequests, v.API.Requests, api)
+	obs.ObserveInt64(in.apiRetries, v.API.Retries, api)
+	obs.ObserveInt64(in.apiRateLimited, v.API.RateLimited, api)
+	obs.ObserveInt64(in.apiFailures, v.API.Failures, api)
+	obs.ObserveInt64(in.apiTrips, v.API.BreakerTrips, api)
+	obs.ObserveInt64(in.apiBreaker, int64(v.API.BreakerState), api)
+
+	for kind, n := range map[string]int64{
+		"auth":       v.API.AuthErrors,
+		"permission": v.API.PermissionErrors,
+		"not_found":  v.API.NotFoundErrors,
+		"server":     v.API.ServerErrors,
+	} {
+		obs.ObserveInt64(in.apiErrors, n, o.attrs(nil, attribute.String("veeam.api.error.kind", kind)))
+	}
+}
+
+// attrs adds the custom tags of the object to the attributes, built-in attributes are never overridden
+func (o *OTLP) attrs(obj filter.Object, kv ...attribute.KeyValue) metric.ObserveOption {
+	custom := o.tagger.Tags(obj)
+
+	keys := make([]string, 0, len(custom))
+	for k := range custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	builtin := make(map[attribute.Key]struct{}, len(kv))
+	for _, a := range kv {
+		builtin[a.Key] = struct{}{}
+	}
+
+	for _, k := range keys {
+		if _, ok := builtin[attribute.Key(k)]; !ok {
+			kv = append(kv, attribute.String(k, custom[k]))
+		}
+	}
+
+	return metric.WithAttributes(kv...)
+}
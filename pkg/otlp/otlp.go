@@ -0,0 +1,251 @@
+// Package otlp exports the collected data as OpenTelemetry metrics to a collector
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/tags"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	defaultTimeout  = 10 * time.Second
+	defaultHTTPPath = "/v1/metrics"
+	meterName       = "github.com/ZeljkoBenovic/govein"
+)
+
+// OTLP exports the data of every collection cycle, the instruments observe the last stored data
+type OTLP struct {
+	ctx     context.Context
+	log     *slog.Logger
+	timeout time.Duration
+
+	exp      sdkmetric.Exporter
+	reader   *sdkmetric.ManualReader
+	provider *sdkmetric.MeterProvider
+
+	// mu guards the data observed by the instruments and the settings swapped by SetConfig
+	mu      sync.Mutex
+	conf    config.Config
+	tagger  *tags.Tagger
+	data    veeam.Veeam
+	lastErr error
+}
+
+func New(ctx context.Context, conf config.Config, log *slog.Logger) (*OTLP, error) {
+	if conf.OTLP == nil {
+		return nil, fmt.Errorf("otlp is not configured")
+	}
+
+	t, err := tags.New(conf.Veeam.Tags, conf.TagRules)
+	if err != nil {
+		return nil, fmt.Errorf("could not create tagger: %v", err)
+	}
+
+	timeout := time.Duration(conf.OTLP.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	exp, err := newExporter(ctx, *conf.OTLP, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewManualReader()
+
+	o := &OTLP{
+		ctx:      ctx,
+		log:      log,
+		timeout:  timeout,
+		exp:      exp,
+		reader:   reader,
+		provider: sdkmetric.NewMeterProvider(sdkmetric.WithResource(res), sdkmetric.WithReader(reader)),
+		conf:     conf,
+		tagger:   t,
+	}
+
+	if err = o.register(o.provider.Meter(meterName)); err != nil {
+		return nil, fmt.Errorf("could not register otlp instruments: %v", err)
+	}
+
+	log.Info("OTLP exporter created", "endpoint", conf.OTLP.Endpoint, "protocol", protocol(*conf.OTLP))
+
+	return o, nil
+}
+
+func protocol(o config.OTLP) string {
+	if o.Protocol == "" {
+		return config.OTLPProtocolGRPC
+	}
+
+	return o.Protocol
+}
+
+func newExporter(ctx context.Context, o config.OTLP, timeout time.Duration) (sdkmetric.Exporter, error) {
+	switch protocol(o) {
+	case config.OTLPProtocolHTTP:
+		u, err := url.Parse(o.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse otlp endpoint: %v", err)
+		}
+
+		path := u.Path
+		if path == "" || path == "/" {
+			path = defaultHTTPPath
+		}
+
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(u.Host),
+			otlpmetrichttp.WithURLPath(path),
+			otlpmetrichttp.WithHeaders(o.Headers),
+			otlpmetrichttp.WithTimeout(timeout),
+		}
+
+		if u.Scheme == "http" {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+
+		exp, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("could not create otlp http exporter: %v", err)
+		}
+
+		return exp, nil
+	case config.OTLPProtocolGRPC:
+		exp, err := otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(o.Endpoint),
+			otlpmetricgrpc.WithHeaders(o.Headers),
+			otlpmetricgrpc.WithTimeout(timeout),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not create otlp grpc exporter: %v", err)
+		}
+
+		return exp, nil
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q", o.Protocol)
+	}
+}
+
+// newResource identifies the vbr server, OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME can add to it
+func newResource(ctx context.Context, conf config.Config) (*resource.Resource, error) {
+	vbr := []attribute.KeyValue{attribute.String("veeam.vbr.server", conf.Veeam.Host)}
+	if u, err := url.Parse(conf.Veeam.Host); err == nil && u.Hostname() != "" {
+		vbr = append(vbr, attribute.String("server.address", u.Hostname()))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "govein")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(vbr...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create otlp resource: %v", err)
+	}
+
+	return res, nil
+}
+
+func (o *OTLP) Name() string {
+	return config.SinkOTLP
+}
+
+// Store exports the data collected by a cycle
+func (o *OTLP) Store(v veeam.Veeam) error {
+	o.log.Info("Exporting metrics to otlp collector")
+
+	o.mu.Lock()
+	o.data = v
+	o.mu.Unlock()
+
+	return o.export()
+}
+
+// StoreStatus exports only the collector states and api client stats, for the cycles failing to collect the rest.
+// The data of the last cycle is dropped, so it is not exported as if it were current, the server info is kept.
+func (o *OTLP) StoreStatus(v veeam.Veeam) error {
+	o.log.Info("Exporting collector states and api client stats to otlp collector")
+
+	o.mu.Lock()
+	o.data = veeam.Veeam{ServerInfo: o.data.ServerInfo, Collectors: v.Collectors, API: v.API}
+	o.mu.Unlock()
+
+	return o.export()
+}
+
+func (o *OTLP) export() error {
+	var rm metricdata.ResourceMetrics
+	if err := o.reader.Collect(o.ctx, &rm); err != nil {
+		return fmt.Errorf("could not collect otlp metrics: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, o.timeout)
+	defer cancel()
+
+	err := o.exp.Export(ctx, &rm)
+
+	o.mu.Lock()
+	o.lastErr = err
+	o.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("could not export otlp metrics: %v", err)
+	}
+
+	return nil
+}
+
+// SetConfig swaps the settings used when exporting data, such as the custom tags.
+// Connection settings need a new exporter instead.
+func (o *OTLP) SetConfig(conf config.Config) error {
+	t, err := tags.New(conf.Veeam.Tags, conf.TagRules)
+	if err != nil {
+		return fmt.Errorf("could not create tagger: %v", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.conf = conf
+	o.tagger = t
+
+	return nil
+}
+
+// Ping returns the error of the last export, OTLP has no health endpoint
+func (o *OTLP) Ping() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.lastErr
+}
+
+func (o *OTLP) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	if err := o.provider.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return o.exp.Shutdown(ctx)
+}
@@ -0,0 +1,220 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/sink/sinktest"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	colmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// receiver is an in-process otlp receiver, serving both grpc and http
+type receiver struct {
+	colmetrics.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*colmetrics.ExportMetricsServiceRequest
+}
+
+func (r *receiver) Export(_ context.Context, req *colmetrics.ExportMetricsServiceRequest) (*colmetrics.ExportMetricsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+
+	return &colmetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v1/metrics" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+
+	var m colmetrics.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(body, &m); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, _ := r.Export(req.Context(), &m)
+	b, _ := proto.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+// metrics returns the number of data points of every exported metric, along with the resource attributes
+func (r *receiver) metrics() (map[string]int, map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	points := make(map[string]int)
+	res := make(map[string]string)
+
+	for _, req := range r.requests {
+		for _, rm := range req.ResourceMetrics {
+			for _, a := range rm.Resource.Attributes {
+				res[a.Key] = a.Value.GetStringValue()
+			}
+
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					switch {
+					case m.GetGauge() != nil:
+						points[m.Name] += len(m.GetGauge().DataPoints)
+					case m.GetSum() != nil:
+						points[m.Name] += len(m.GetSum().DataPoints)
+					}
+				}
+			}
+		}
+	}
+
+	return points, res
+}
+
+func TestStore(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+	conf.Veeam.Tags = map[string]string{"site": "ams"}
+
+	rcv := &receiver{}
+
+	httpSrv := httptest.NewServer(rcv)
+	t.Cleanup(httpSrv.Close)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grpcSrv := grpc.NewServer()
+	colmetrics.RegisterMetricsServiceServer(grpcSrv, rcv)
+	go func() { _ = grpcSrv.Serve(lis) }()
+	t.Cleanup(grpcSrv.Stop)
+
+	endpoints := []config.OTLP{
+		{Endpoint: httpSrv.URL, Protocol: config.OTLPProtocolHTTP},
+		{Endpoint: "http://" + lis.Addr().String()},
+	}
+
+	for _, e := range endpoints {
+		rcv.mu.Lock()
+		rcv.requests = nil
+		rcv.mu.Unlock()
+
+		conf.OTLP = &e
+
+		o, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = o.Store(v); err != nil {
+			t.Fatalf("%s: %v", protocol(e), err)
+		}
+
+		if err = o.Ping(); err != nil {
+			t.Errorf("%s: expected ping to succeed after an export, got %v", protocol(e), err)
+		}
+
+		_ = o.Close()
+
+		points, res := rcv.metrics()
+
+		if res["veeam.vbr.server"] != conf.Veeam.Host || res["service.name"] != "govein" {
+			t.Errorf("%s: expected resource to identify the vbr server, got %v", protocol(e), res)
+		}
+
+		for _, name := range []string{
+			"veeam.vbr.info", "veeam.vbr.sessions", "veeam.vbr.job.last_result", "veeam.vbr.job.last_duration",
+			"veeam.vbr.managed_servers", "veeam.vbr.repository.capacity", "veeam.vbr.proxy.tasks.max",
+			"veeam.vbr.proxy.online", "veeam.vbr.backup_object.restore_points",
+		} {
+			if points[name] == 0 {
+				t.Errorf("%s: expected data points of %s, got %v", protocol(e), name, points)
+			}
+		}
+
+		if want := len(v.BackupObjects.Data); points["veeam.vbr.backup_object.restore_points"] != want {
+			t.Errorf("%s: expected %d restore points data points, got %d", protocol(e), want, points["veeam.vbr.backup_object.restore_points"])
+		}
+	}
+}
+
+func TestStoreExportError(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+
+	conf.OTLP = &config.OTLP{Endpoint: srv.URL, Protocol: config.OTLPProtocolHTTP}
+
+	o, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if err = o.Store(v); err == nil {
+		t.Fatal("expected export error")
+	}
+
+	if o.Ping() == nil {
+		t.Error("expected ping to report the failed export")
+	}
+}
+
+func TestStoreStatus(t *testing.T) {
+	v, conf := sinktest.Collected(t)
+
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	t.Cleanup(srv.Close)
+
+	conf.OTLP = &config.OTLP{Endpoint: srv.URL, Protocol: config.OTLPProtocolHTTP}
+
+	o, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if err = o.Store(v); err != nil {
+		t.Fatal(err)
+	}
+
+	rcv.mu.Lock()
+	rcv.requests = nil
+	rcv.mu.Unlock()
+
+	failed := veeam.Veeam{
+		Collectors: []veeam.CollectorStatus{{Name: veeam.CollectorJobs, State: veeam.CollectorFailed}},
+		API:        veeam.APIStats{Failures: 3},
+	}
+
+	if err = o.StoreStatus(failed); err != nil {
+		t.Fatal(err)
+	}
+
+	// the data of the last cycle is not exported again with the status of a failed one
+	points, _ := rcv.metrics()
+	if points["veeam.vbr.api.failures"] == 0 || points["veeam.vbr.collector.state"] != 1 ||
+		points["veeam.vbr.sessions"] != 0 || points["veeam.vbr.proxy.online"] != 0 {
+		t.Errorf("expected the collector states and api client stats only, got %v", points)
+	}
+}
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationLock is the advisory lock key held while migrating, so replicas do not migrate concurrently
+const migrationLock = 7_415_726_401
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+	// timescale runs after sql when the timescaledb extension is installed
+	timescale string
+}
+
+// migrations are applied in order and never changed once released, add a new one instead
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create tables",
+		sql: `
+CREATE TABLE vbr_servers (
+	host            text PRIMARY KEY,
+	vbr_id          text NOT NULL,
+	name            text NOT NULL,
+	version         text NOT NULL,
+	database_vendor text NOT NULL,
+	last_seen_at    timestamptz NOT NULL
+);
+
+CREATE TABLE jobs (
+	id           text PRIMARY KEY,
+	vbr_host     text NOT NULL REFERENCES vbr_servers (host),
+	name         text NOT NULL,
+	type         text NOT NULL,
+	description  text NOT NULL,
+	is_disabled  boolean NOT NULL,
+	last_seen_at timestamptz NOT NULL
+);
+
+-- job_id is not a foreign key, the job of a session can be deleted or filtered out
+CREATE TABLE sessions (
+	id               text PRIMARY KEY,
+	vbr_host         text NOT NULL REFERENCES vbr_servers (host),
+	job_id           text,
+	name             text NOT NULL,
+	session_type     text NOT NULL,
+	platform         text NOT NULL,
+	state            text NOT NULL,
+	result           text NOT NULL,
+	result_message   text NOT NULL,
+	creation_time    timestamptz NOT NULL,
+	end_time         timestamptz,
+	duration_seconds double precision,
+	last_seen_at     timestamptz NOT NULL
+);
+
+CREATE INDEX sessions_job_id_idx ON sessions (job_id);
+CREATE INDEX sessions_creation_time_idx ON sessions (creation_time DESC);
+
+CREATE TABLE managed_servers (
+	id           text PRIMARY KEY,
+	vbr_host     text NOT NULL REFERENCES vbr_servers (host),
+	name         text NOT NULL,
+	type         text NOT NULL,
+	description  text NOT NULL,
+	status       text NOT NULL,
+	last_seen_at timestamptz NOT NULL
+);
+
+CREATE TABLE repositories (
+	id             text PRIMARY KEY,
+	vbr_host       text NOT NULL REFERENCES vbr_servers (host),
+	name           text NOT NULL,
+	type           text NOT NULL,
+	description    text NOT NULL,
+	host_id        text,
+	path           text,
+	per_vm_backup  boolean NOT NULL,
+	max_task_count bigint NOT NULL,
+	capacity_bytes double precision,
+	free_bytes     double precision,
+	used_bytes     double precision,
+	is_online      boolean,
+	last_seen_at   timestamptz NOT NULL
+);
+
+CREATE TABLE proxies (
+	id             text PRIMARY KEY,
+	vbr_host       text NOT NULL REFERENCES vbr_servers (host),
+	name           text NOT NULL,
+	type           text NOT NULL,
+	description    text NOT NULL,
+	host_id        text NOT NULL,
+	host_name      text NOT NULL,
+	transport_mode text NOT NULL,
+	max_task_count bigint NOT NULL,
+	last_seen_at   timestamptz NOT NULL
+);
+
+CREATE TABLE backup_objects (
+	id                   text PRIMARY KEY,
+	vbr_host             text NOT NULL REFERENCES vbr_servers (host),
+	object_id            text NOT NULL,
+	name                 text NOT NULL,
+	type                 text NOT NULL,
+	platform             text NOT NULL,
+	vi_type              text NOT NULL,
+	path                 text NOT NULL,
+	restore_points_count bigint NOT NULL,
+	last_seen_at         timestamptz NOT NULL
+);
+
+CREATE TABLE repository_history (
+	time           timestamptz NOT NULL,
+	repository_id  text NOT NULL REFERENCES repositories (id),
+	capacity_bytes double precision,
+	free_bytes     double precision,
+	used_bytes     double precision,
+	is_online      boolean
+);
+
+CREATE INDEX repository_history_repository_id_time_idx ON repository_history (repository_id, time DESC);
+
+CREATE TABLE proxy_history (
+	time           timestamptz NOT NULL,
+	proxy_id       text NOT NULL REFERENCES proxies (id),
+	is_online      boolean NOT NULL,
+	is_disabled    boolean NOT NULL,
+	is_out_of_date boolean NOT NULL
+);
+
+CREATE INDEX proxy_history_proxy_id_time_idx ON proxy_history (proxy_id, time DESC);
+
+CREATE TABLE backup_object_history (
+	time                 timestamptz NOT NULL,
+	backup_object_id     text NOT NULL REFERENCES backup_objects (id),
+	restore_points_count bigint NOT NULL
+);
+
+CREATE INDEX backup_object_history_backup_object_id_time_idx ON backup_object_history (backup_object_id, time DESC);
+`,
+		timescale: `
+SELECT create_hypertable('repository_history', 'time', if_not_exists => TRUE, migrate_data => TRUE);
+SELECT create_hypertable('proxy_history', 'time', if_not_exists => TRUE, migrate_data => TRUE);
+SELECT create_hypertable('backup_object_history', 'time', if_not_exists => TRUE, migrate_data => TRUE);
+`,
+	},
+}
+
+// migrate applies the migrations missing from the database, each in its own transaction
+func (p *Postgres) migrate(ctx context.Context) error {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("could not acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("could not lock migrations: %v", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+	}()
+
+	if _, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS govein_schema_migrations (
+	version    integer PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`); err != nil {
+		return fmt.Errorf("could not create migrations table: %v", err)
+	}
+
+	var current int
+	if err = conn.QueryRow(ctx, "SELECT coalesce(max(version), 0) FROM govein_schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("could not read schema version: %v", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		p.log.Info("Migrating postgres schema", "version", m.version, "name", m.name)
+
+		if err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.sql); err != nil {
+				return err
+			}
+
+			if p.timescale && m.timescale != "" {
+				if _, err := tx.Exec(ctx, m.timescale); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Exec(ctx, "INSERT INTO govein_schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name)
+			return err
+		}); err != nil {
+			return fmt.Errorf("could not apply migration %d %s: %v", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
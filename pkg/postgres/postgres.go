@@ -0,0 +1,92 @@
+// Package postgres stores the collected data in normalized PostgreSQL tables.
+// Current state tables are upserted by their Veeam ids, history tables are hypertables on TimescaleDB.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Postgres struct {
+	ctx  context.Context
+	log  *slog.Logger
+	pool *pgxpool.Pool
+	// timescale is set when the timescaledb extension is installed
+	timescale bool
+
+	mu   sync.RWMutex
+	conf config.Config
+}
+
+// New connects to the database and migrates its tables
+func New(ctx context.Context, conf config.Config, log *slog.Logger) (*Postgres, error) {
+	if conf.Postgres == nil {
+		return nil, fmt.Errorf("postgres is not configured")
+	}
+
+	pool, err := pgxpool.New(ctx, conf.Postgres.URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse postgres url: %v", err)
+	}
+
+	p := &Postgres{
+		ctx:  ctx,
+		log:  log,
+		pool: pool,
+		conf: conf,
+	}
+
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("could not connect to postgres: %v", err)
+	}
+
+	var version string
+	if err = pool.QueryRow(ctx, "SHOW server_version").Scan(&version); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("could not read postgres version: %v", err)
+	}
+
+	if err = pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')").Scan(&p.timescale); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("could not look up timescaledb extension: %v", err)
+	}
+
+	log.Info("PostgreSQL server info", "version", version, "timescaledb", p.timescale)
+
+	if err = p.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Postgres) Name() string {
+	return config.SinkPostgres
+}
+
+// SetConfig swaps the settings used when storing data, the url needs a new sink instead
+func (p *Postgres) SetConfig(conf config.Config) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.conf = conf
+
+	return nil
+}
+
+func (p *Postgres) Ping() error {
+	return p.pool.Ping(p.ctx)
+}
+
+func (p *Postgres) Close() error {
+	p.pool.Close()
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	upsertServer = `INSERT INTO vbr_servers (host, vbr_id, name, version, database_vendor, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (host) DO UPDATE SET vbr_id = EXCLUDED.vbr_id, name = EXCLUDED.name, version = EXCLUDED.version,
+	database_vendor = EXCLUDED.database_vendor, last_seen_at = EXCLUDED.last_seen_at`
+
+	upsertJob = `INSERT INTO jobs (id, vbr_host, name, type, description, is_disabled, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, name = EXCLUDED.name, type = EXCLUDED.type,
+	description = EXCLUDED.description, is_disabled = EXCLUDED.is_disabled, last_seen_at = EXCLUDED.last_seen_at`
+
+	upsertSession = `INSERT INTO sessions (id, vbr_host, job_id, name, session_type, platform, state, result, result_message,
+	creation_time, end_time, duration_seconds, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, job_id = EXCLUDED.job_id, name = EXCLUDED.name,
+	session_type = EXCLUDED.session_type, platform = EXCLUDED.platform, state = EXCLUDED.state, result = EXCLUDED.result,
+	result_message = EXCLUDED.result_message, creation_time = EXCLUDED.creation_time, end_time = EXCLUDED.end_time,
+	duration_seconds = EXCLUDED.duration_seconds, last_seen_at = EXCLUDED.last_seen_at`
+
+	upsertManagedServer = `INSERT INTO managed_servers (id, vbr_host, name, type, description, status, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, name = EXCLUDED.name, type = EXCLUDED.type,
+	description = EXCLUDED.description, status = EXCLUDED.status, last_seen_at = EXCLUDED.last_seen_at`
+
+	upsertRepository = `INSERT INTO repositories (id, vbr_host, name, type, description, host_id, path, per_vm_backup, max_task_count,
+	capacity_bytes, free_bytes, used_bytes, is_online, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, name = EXCLUDED.name, type = EXCLUDED.type,
+	description = EXCLUDED.description, host_id = EXCLUDED.host_id, path = EXCLUDED.path, per_vm_backup = EXCLUDED.per_vm_backup,
+	max_task_count = EXCLUDED.max_task_count, capacity_bytes = EXCLUDED.capacity_bytes, free_bytes = EXCLUDED.free_bytes,
+	used_bytes = EXCLUDED.used_bytes, is_online = EXCLUDED.is_online, last_seen_at = EXCLUDED.last_seen_at`
+
+	insertRepositoryHistory = `INSERT INTO repository_history (time, repository_id, capacity_bytes, free_bytes, used_bytes, is_online)
+VALUES ($1, $2, $3, $4, $5, $6)`
+
+	upsertProxy = `INSERT INTO proxies (id, vbr_host, name, type, description, host_id, host_name, transport_mode, max_task_count, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, name = EXCLUDED.name, type = EXCLUDED.type,
+	description = EXCLUDED.description, host_id = EXCLUDED.host_id, host_name = EXCLUDED.host_name,
+	transport_mode = EXCLUDED.transport_mode, max_task_count = EXCLUDED.max_task_count, last_seen_at = EXCLUDED.last_seen_at`
+
+	insertProxyHistory = `INSERT INTO proxy_history (time, proxy_id, is_online, is_disabled, is_out_of_date)
+VALUES ($1, $2, $3, $4, $5)`
+
+	upsertBackupObject = `INSERT INTO backup_objects (id, vbr_host, object_id, name, type, platform, vi_type, path, restore_points_count, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (id) DO UPDATE SET vbr_host = EXCLUDED.vbr_host, object_id = EXCLUDED.object_id, name = EXCLUDED.name,
+	type = EXCLUDED.type, platform = EXCLUDED.platform, vi_type = EXCLUDED.vi_type, path = EXCLUDED.path,
+	restore_points_count = EXCLUDED.restore_points_count, last_seen_at = EXCLUDED.last_seen_at`
+
+	insertBackupObjectHistory = `INSERT INTO backup_object_history (time, backup_object_id, restore_points_count)
+VALUES ($1, $2, $3)`
+)
+
+// Store upserts the collected objects and appends their history in a single transaction
+func (p *Postgres) Store(v veeam.Veeam) error {
+	p.log.Info("Storing data into postgres")
+
+	p.mu.RLock()
+	host := p.conf.Veeam.Host
+	p.mu.RUnlock()
+
+	b := batch(host, v, time.Now().UTC())
+
+	if err := pgx.BeginFunc(p.ctx, p.pool, func(tx pgx.Tx) error {
+		return tx.SendBatch(p.ctx, b).Close()
+	}); err != nil {
+		return fmt.Errorf("could not store data in postgres: %v", err)
+	}
+
+	return nil
+}
+
+// batch queues the statements storing the collected data, parents are queued before the rows referencing them
+func batch(host string, v veeam.Veeam, now time.Time) *pgx.Batch {
+	b := &pgx.Batch{}
+
+	info := v.ServerInfo
+	b.Queue(upsertServer, host, info.VbrId, info.Name, info.BuildVersion, info.DatabaseVendor, now)
+
+	for _, j := range v.Jobs.Data {
+		b.Queue(upsertJob, j.ID, host, j.Name, j.Type, j.Description, j.IsDisabled, now)
+	}
+
+	for _, s := range v.Sessions.Data {
+		var (
+			end      *time.Time
+			duration *float64
+		)
+
+		// running sessions have no end time yet
+		if !s.EndTime.IsZero() {
+			end = &s.EndTime
+			d := s.EndTime.Sub(s.CreationTime).Seconds()
+			duration = &d
+		}
+
+		b.Queue(upsertSession, s.ID, host, nullable(s.JobID), s.Name, s.SessionType, s.PlatformName, s.State,
+			s.Result.Result, s.Result.Message, s.CreationTime, end, duration, now)
+	}
+
+	for _, s := range v.ManagedSevers.Data {
+		b.Queue(upsertManagedServer, s.ID, host, s.Name, s.Type, s.Description, s.Status, now)
+	}
+
+	states := make(map[string]veeam.SingleRepositoryData)
+	for _, r := range v.Repositories {
+		for _, rd := range r.Data {
+			states[rd.ID] = rd
+		}
+	}
+
+	for _, r := range v.AllRepositories.Data {
+		var (
+			capacity, free, used *float64
+			online               *bool
+		)
+
+		hostID, path := r.HostID, r.Repository.Path
+
+		// state is missing for repository types veeam reports no capacity of
+		rd, ok := states[r.ID]
+		if ok {
+			trimmed := strings.TrimRight(rd.Path, "\\")
+			path = &trimmed
+			c, f, u := rd.CapacityGB*1024*1024*1024, rd.FreeGB*1024*1024*1024, rd.UsedSpaceGB*1024*1024*1024
+			capacity, free, used = &c, &f, &u
+			online = &rd.IsOnline
+		}
+
+		b.Queue(upsertRepository, r.ID, host, r.Name, r.Type, r.Description, hostID, path,
+			r.Repository.AdvancedSettings.PerVMBackup, r.Repository.MaxTaskCount, capacity, free, used, online, now)
+
+		if ok {
+			b.Queue(insertRepositoryHistory, now, r.ID, capacity, free, used, online)
+		}
+	}
+
+	for _, pr := range v.Proxies.Data {
+		hostName := pr.Server.HostID
+		if h, ok := v.ManagedSevers.ServerByID(pr.Server.HostID); ok {
+			hostName = h.Name
+		}
+
+		b.Queue(upsertProxy, pr.ID, host, pr.Name, pr.Type, pr.Description, pr.Server.HostID, hostName,
+			pr.Server.TransportMode, pr.Server.MaxTaskCount, now)
+
+		for _, s := range v.ProxyStates.Data {
+			if s.ID == pr.ID {
+				b.Queue(insertProxyHistory, now, pr.ID, s.IsOnline, s.IsDisabled, s.IsOutOfDate)
+				break
+			}
+		}
+	}
+
+	for _, o := range v.BackupObjects.Data {
+		b.Queue(upsertBackupObject, o.ID, host, o.ObjectID, o.Name, string(o.Type), string(o.PlatformName), string(o.ViType),
+			o.Path, o.RestorePointsCount, now)
+		b.Queue(insertBackupObjectHistory, now, o.ID, o.RestorePointsCount)
+	}
+
+	return b
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
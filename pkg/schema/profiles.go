@@ -0,0 +1,153 @@
+package schema
+
+// snakeCase renames every legacy name, measurements keep the veeam_vbr_ prefix,
+// tags and fields drop it as the measurement already tells what they belong to
+var snakeCase = Schema{
+	Measurements: map[string]string{
+		"veeam_vbr_managedservers":     "veeam_vbr_managed_servers",
+		"veeam_vbr_backupobjects":      "veeam_vbr_backup_objects",
+		"veeam_vbr_fileshare_jobs":     "veeam_vbr_file_share_jobs",
+		"veeam_vbr_fileshare_sessions": "veeam_vbr_file_share_sessions",
+	},
+	Tags: map[string]string{
+		"veeamVBR":            "vbr_server",
+		"veeamVBRId":          "vbr_id",
+		"veeamVBRName":        "vbr_name",
+		"veeamVBRVersion":     "vbr_version",
+		"veeamDatabaseVendor": "database_vendor",
+
+		"veeamVBRSessionJobName":           "job_name",
+		"veeamVBRSessiontype":              "session_type",
+		"veeamVBRSessionsJobState":         "state",
+		"veeamVBRSessionsJobResultMessage": "result_message",
+
+		"veeamVBRTaskObjectName":    "object_name",
+		"veeamVBRTaskSessiontype":   "session_type",
+		"veeamVBRTaskState":         "state",
+		"veeamVBRTaskResultMessage": "result_message",
+
+		"veeamVBRCollector": "collector",
+
+		"veeamVBRMSName":        "name",
+		"veeamVBRMStype":        "type",
+		"veeamVBRMSDescription": "description",
+
+		"veeamVBRRepoName":  "name",
+		"veeamVBRRepoType":  "type",
+		"veeamVBRRepopath":  "path",
+		"veeamVBRRepoPerVM": "per_vm_backup",
+
+		"veeamVBRProxyName":        "name",
+		"veeamVBRProxyType":        "type",
+		"veeamVBRProxyDescription": "description",
+		"veeamVBRProxyMode":        "transport_mode",
+		"veeamVBRProxyHost":        "host",
+		"veeamVBRProxyDisabled":    "disabled",
+		"veeamVBRProxyOutOfDate":   "out_of_date",
+
+		"veeamVBRWanName":          "name",
+		"veeamVBRWanDescription":   "description",
+		"veeamVBRWanHost":          "host",
+		"veeamVBRWanHighBandwidth": "high_bandwidth",
+
+		"veeamVBRBobjectName":     "name",
+		"veeamVBRBobjecttype":     "type",
+		"veeamVBRBobjectPlatform": "platform",
+		"veeamVBRBobjectviType":   "vi_type",
+		"veeamVBRBobjectKind":     "kind",
+		"veeamVBRBobjectObjectId": "object_id",
+		"veeamVBRBobjectPath":     "path",
+
+		"veeamVBRUDSName":            "name",
+		"veeamVBRUDSType":            "type",
+		"veeamVBRUDSObjectStorage":   "object_storage",
+		"veeamVBRUDSProxyAutoSelect": "proxy_auto_select",
+
+		"veeamVBRFSJobName":        "name",
+		"veeamVBRFSJobType":        "type",
+		"veeamVBRFSJobDescription": "description",
+		"veeamVBRFSJobDisabled":    "disabled",
+
+		"veeamVBRConfigBackupEnabled":    "enabled",
+		"veeamVBRConfigBackupEncrypted":  "encrypted",
+		"veeamVBRConfigBackupRepository": "repository",
+		"veeamVBRConfigBackupLastResult": "last_result",
+
+		"veeamVBRUnprotectedName":     "name",
+		"veeamVBRUnprotectedHost":     "host",
+		"veeamVBRUnprotectedObjectId": "object_id",
+		"veeamVBRUnprotectedPlatform": "platform",
+
+		"veeamVBRCredsId":          "id",
+		"veeamVBRCredsUsername":    "username",
+		"veeamVBRCredsType":        "type",
+		"veeamVBRCredsDescription": "description",
+
+		"veeamVBRCertSubject":    "subject",
+		"veeamVBRCertIssuer":     "issuer",
+		"veeamVBRCertSerial":     "serial_number",
+		"veeamVBRCertThumbprint": "thumbprint",
+	},
+	Fields: map[string]string{
+		"vbr": "up",
+
+		"veeamVBRSessionsJobResult":       "result",
+		"veeamBackupSessionsTimeDuration": "duration_seconds",
+
+		"veeamVBRTaskResult":   "result",
+		"veeamVBRTaskDuration": "duration_seconds",
+
+		"veeamVBRMSInternalID": "index",
+
+		"veeamVBRRepoMaxtasks": "max_tasks",
+		"veeamVBRRepoCapacity": "capacity_bytes",
+		"veeamVBRRepoFree":     "free_bytes",
+		"veeamVBRRepoUsed":     "used_bytes",
+
+		"veeamVBRProxyTask":   "max_tasks",
+		"veeamVBRProxyOnline": "online",
+
+		"veeamVBRWanOnline":    "online",
+		"veeamVBRWanStreams":   "streams",
+		"veeamVBRWanCacheSize": "cache_size_bytes",
+
+		"restorePointsCount": "restore_points",
+
+		"veeamVBRUDSProxies": "proxies",
+
+		"veeamVBRFSJobObjects": "objects",
+
+		"veeamVBRConfigBackupResult":         "result",
+		"veeamVBRConfigBackupRestorePoints":  "restore_points",
+		"veeamVBRConfigBackupLastSuccessAge": "last_success_age_seconds",
+		"veeamVBRConfigBackupHasSuccess":     "has_success",
+
+		"veeamVBRUnprotected": "unprotected",
+
+		"veeamVBRProtectionTotal":       "total",
+		"veeamVBRProtectionProtected":   "protected",
+		"veeamVBRProtectionUnprotected": "unprotected",
+		"veeamVBRProtectionCoverage":    "coverage_percent",
+
+		"veeamVBRCredsCreatedAge": "created_age_seconds",
+
+		"veeamVBRCertExpiresIn": "expires_in_seconds",
+		"veeamVBRCertDaysLeft":  "days_left",
+		"veeamVBRCertAge":       "age_seconds",
+
+		"veeamVBRCollectorState": "state",
+		"veeamVBRCollectorError": "error",
+
+		"veeamVBRApiRequests":     "requests_total",
+		"veeamVBRApiRetries":      "retries_total",
+		"veeamVBRApiRateLimited":  "rate_limited_total",
+		"veeamVBRApiFailures":     "failures_total",
+		"veeamVBRApiBreakerState": "breaker_state",
+		"veeamVBRApiBreakerTrips": "breaker_trips_total",
+
+		"veeamVBRApiAuthErrors":       "auth_errors_total",
+		"veeamVBRApiPermissionErrors": "permission_errors_total",
+		"veeamVBRApiNotFoundErrors":   "not_found_errors_total",
+		"veeamVBRApiServerErrors":     "server_errors_total",
+	},
+}
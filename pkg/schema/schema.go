@@ -0,0 +1,131 @@
+// Package schema renames the measurements, tags and fields written by govein.
+// Names are always looked up by their legacy name, the one the original dashboards use.
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Legacy keeps the names used by the original dashboards
+	Legacy = "legacy"
+	// SnakeCase uses snake_case names without the veeamVBR prefixes
+	SnakeCase = "snake_case"
+)
+
+// Profiles lists the built-in profiles
+var Profiles = []string{Legacy, SnakeCase}
+
+// Schema maps legacy names to the names written, names missing from the maps are kept as they are
+type Schema struct {
+	Measurements map[string]string `yaml:"measurements,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+	Fields       map[string]string `yaml:"fields,omitempty"`
+}
+
+// Load returns the schema of the profile, with the mapping file, if any, applied on top of it
+func Load(profile, mappingFile string) (*Schema, error) {
+	s, err := Profile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if mappingFile == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(mappingFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema mapping file: %v", err)
+	}
+
+	var m Schema
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err = dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse schema mapping file: %v", err)
+	}
+
+	merge(s.Measurements, m.Measurements)
+	merge(s.Tags, m.Tags)
+	merge(s.Fields, m.Fields)
+
+	return s, nil
+}
+
+// Profile returns a copy of a built-in profile
+func Profile(name string) (*Schema, error) {
+	s := &Schema{
+		Measurements: make(map[string]string),
+		Tags:         make(map[string]string),
+		Fields:       make(map[string]string),
+	}
+
+	switch name {
+	case Legacy, "":
+	case SnakeCase:
+		merge(s.Measurements, snakeCase.Measurements)
+		merge(s.Tags, snakeCase.Tags)
+		merge(s.Fields, snakeCase.Fields)
+	default:
+		return nil, fmt.Errorf("unknown schema profile %q, must be one of %s or %s", name, Legacy, SnakeCase)
+	}
+
+	return s, nil
+}
+
+func merge(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// Measurement returns the name written for the legacy measurement name, a nil schema keeps every name
+func (s *Schema) Measurement(name string) string {
+	return lookup(s, func(s *Schema) map[string]string { return s.Measurements }, name)
+}
+
+// Tag returns the name written for the legacy tag name
+func (s *Schema) Tag(name string) string {
+	return lookup(s, func(s *Schema) map[string]string { return s.Tags }, name)
+}
+
+// Field returns the name written for the legacy field name
+func (s *Schema) Field(name string) string {
+	return lookup(s, func(s *Schema) map[string]string { return s.Fields }, name)
+}
+
+func lookup(s *Schema, names func(*Schema) map[string]string, name string) string {
+	if s == nil {
+		return name
+	}
+
+	if renamed, ok := names(s)[name]; ok && renamed != "" {
+		return renamed
+	}
+
+	return name
+}
+
+// IsIdentity reports whether the schema keeps every name as it is
+func (s *Schema) IsIdentity() bool {
+	if s == nil {
+		return true
+	}
+
+	for _, m := range []map[string]string{s.Measurements, s.Tags, s.Fields} {
+		for k, v := range m {
+			if v != "" && k != v {
+				return false
+			}
+		}
+	}
+
+	return true
+}
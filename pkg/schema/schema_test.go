@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	mapping := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(mapping, []byte("measurements:\n  veeam_vbr_sessions: backup_sessions\ntags:\n  veeamVBR: server\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(SnakeCase, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct{ got, want string }{
+		// the mapping file overrides the profile
+		{s.Measurement("veeam_vbr_sessions"), "backup_sessions"},
+		{s.Tag("veeamVBR"), "server"},
+		{s.Measurement("veeam_vbr_backupobjects"), "veeam_vbr_backup_objects"},
+		{s.Field("veeamVBRRepoFree"), "free_bytes"},
+		// unknown names are kept
+		{s.Tag("site"), "site"},
+	}
+
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("expected %q, got %q", c.want, c.got)
+		}
+	}
+
+	if s, err = Load(Legacy, ""); err != nil || !s.IsIdentity() {
+		t.Errorf("expected legacy profile to keep every name, got %v, %v", s, err)
+	}
+
+	var nilSchema *Schema
+	if nilSchema.Tag("veeamVBR") != "veeamVBR" {
+		t.Error("expected nil schema to keep every name")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load("camelCase", ""); err == nil {
+		t.Error("expected unknown profile error")
+	}
+
+	mapping := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(mapping, []byte("metrics:\n  vbr: up\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(Legacy, mapping); err == nil {
+		t.Error("expected unknown key error")
+	}
+}
+
+func TestSnakeCaseNames(t *testing.T) {
+	re := regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+	for _, m := range []map[string]string{snakeCase.Measurements, snakeCase.Tags, snakeCase.Fields} {
+		for k, v := range m {
+			if !re.MatchString(v) {
+				t.Errorf("%s: %q is not snake_case", k, v)
+			}
+		}
+	}
+}
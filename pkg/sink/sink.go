@@ -0,0 +1,176 @@
+// Package sink stores the data of every collection cycle in the configured backends
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/file"
+	"github.com/ZeljkoBenovic/govein/pkg/influx"
+	"github.com/ZeljkoBenovic/govein/pkg/otlp"
+	"github.com/ZeljkoBenovic/govein/pkg/postgres"
+	"github.com/ZeljkoBenovic/govein/pkg/sqlite"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// Sink is a backend the collected data is stored in
+type Sink interface {
+	// Name of the sink, as set in the sinks config key
+	Name() string
+	// Store stores the data collected by a cycle
+	Store(v veeam.Veeam) error
+	// SetConfig swaps the settings used when storing data, connection settings need a new sink instead
+	SetConfig(conf config.Config) error
+	Ping() error
+	Close() error
+}
+
+// StatusStorer is implemented by the sinks exporting the collector states and api client stats,
+// which are stored on failed cycles too, so the failed collectors and the breaker state are visible
+type StatusStorer interface {
+	StoreStatus(v veeam.Veeam) error
+}
+
+// New connects to every configured sink, the sinks already connected are closed if one fails
+func New(ctx context.Context, conf config.Config, log *slog.Logger) ([]Sink, error) {
+	var sinks []Sink
+
+	for _, name := range conf.Sinks {
+		s, err := newSink(ctx, name, conf, log)
+		if err != nil {
+			Close(sinks)
+			return nil, err
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+func newSink(ctx context.Context, name string, conf config.Config, log *slog.Logger) (Sink, error) {
+	switch name {
+	case config.SinkInflux:
+		i, err := influx.NewInflux(ctx, conf, log)
+		if err != nil {
+			return nil, fmt.Errorf("could not create influx client: %v", err)
+		}
+		return i, nil
+	case config.SinkOTLP:
+		o, err := otlp.New(ctx, conf, log)
+		if err != nil {
+			return nil, fmt.Errorf("could not create otlp exporter: %v", err)
+		}
+		return o, nil
+	case config.SinkPostgres:
+		p, err := postgres.New(ctx, conf, log)
+		if err != nil {
+			return nil, fmt.Errorf("could not create postgres sink: %v", err)
+		}
+		return p, nil
+	case config.SinkSQLite:
+		s, err := sqlite.New(ctx, conf, log)
+		if err != nil {
+			return nil, fmt.Errorf("could not create sqlite sink: %v", err)
+		}
+		return s, nil
+	case config.SinkFile:
+		f, err := file.New(ctx, conf, log)
+		if err != nil {
+			return nil, fmt.Errorf("could not create file sink: %v", err)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unknown sink %q", name)
+	}
+}
+
+// Close closes the sinks, errors are ignored as there is nothing left to do with them
+func Close(sinks []Sink) {
+	for _, s := range sinks {
+		_ = s.Close()
+	}
+}
+
+// Changed reports whether the sinks or their connection settings changed, which needs new sinks
+func Changed(old, new config.Config) bool {
+	if !slices.Equal(old.Sinks, new.Sinks) {
+		return true
+	}
+
+	if new.HasSink(config.SinkInflux) && influxChanged(old.Influx, new.Influx) {
+		return true
+	}
+
+	if new.HasSink(config.SinkOTLP) && !reflect.DeepEqual(old.OTLP, new.OTLP) {
+		return true
+	}
+
+	if new.HasSink(config.SinkPostgres) && !reflect.DeepEqual(old.Postgres, new.Postgres) {
+		return true
+	}
+
+	if new.HasSink(config.SinkSQLite) && sqlitePath(old) != sqlitePath(new) {
+		return true
+	}
+
+	if new.HasSink(config.SinkFile) && !reflect.DeepEqual(old.File, new.File) {
+		return true
+	}
+
+	return false
+}
+
+func sqlitePath(conf config.Config) string {
+	if conf.SQLite == nil {
+		return ""
+	}
+
+	return conf.SQLite.Path
+}
+
+// StoreStatus stores the collector states and api client stats in every sink exporting them
+func StoreStatus(sinks []Sink, v veeam.Veeam) error {
+	var errs []error
+	for _, s := range sinks {
+		st, ok := s.(StatusStorer)
+		if !ok {
+			continue
+		}
+
+		if err := st.StoreStatus(v); err != nil {
+			errs = append(errs, fmt.Errorf("could not store status in %s: %v", s.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Handler returns the http handler of the first sink serving its stored history
+func Handler(sinks []Sink) (http.Handler, bool) {
+	for _, s := range sinks {
+		if h, ok := s.(interface{ Handler() http.Handler }); ok {
+			return h.Handler(), true
+		}
+	}
+
+	return nil, false
+}
+
+func influxChanged(old, new config.Influx) bool {
+	return old.Version != new.Version ||
+		old.Host != new.Host ||
+		old.Token != new.Token ||
+		old.Org != new.Org ||
+		old.Bucket != new.Bucket ||
+		old.Username != new.Username ||
+		old.Password != new.Password ||
+		old.Database != new.Database ||
+		old.RetentionPolicy != new.RetentionPolicy
+}
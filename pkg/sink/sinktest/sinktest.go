@@ -0,0 +1,40 @@
+// Package sinktest provides the data of a collection cycle against the fake Veeam B&R REST API, for sink tests.
+// It lives outside of veeamtest, which the veeam package tests import and so can not import veeam itself.
+package sinktest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+)
+
+// Collected runs a collection cycle against a fake server loaded with the default fixtures,
+// it returns the collected data and the config pointing to the server.
+// setup changes the server before the cycle, e.g. to inject errors.
+func Collected(t *testing.T, setup ...func(srv *veeamtest.Server)) (veeam.Veeam, config.Config) {
+	t.Helper()
+
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	for _, fn := range setup {
+		fn(srv)
+	}
+
+	v, err := veeam.NewVeeam(context.Background(), srv.Config(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	if err = v.Collect(); err != nil {
+		t.Fatal(err)
+	}
+
+	return *v, srv.Config()
+}
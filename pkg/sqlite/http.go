@@ -0,0 +1,372 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultHistoryRange = 24 * time.Hour
+
+// Handler serves the stored history and reports as json:
+//
+//	GET /api/v1/snapshots?limit=100
+//	GET /api/v1/history/{sessions,repositories,proxies,backup_objects}?from=&to=&name=
+//	GET /api/v1/reports/jobs?from=&to=
+//	GET /api/v1/reports/repositories?from=&to=
+//
+// from and to are RFC 3339 times, the last 24 hours by default. name filters the history by object name.
+func (s *SQLite) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/snapshots", s.snapshots)
+	mux.HandleFunc("GET /api/v1/history/sessions", s.sessionHistory)
+	mux.HandleFunc("GET /api/v1/history/repositories", s.repositoryHistory)
+	mux.HandleFunc("GET /api/v1/history/proxies", s.proxyHistory)
+	mux.HandleFunc("GET /api/v1/history/backup_objects", s.backupObjectHistory)
+	mux.HandleFunc("GET /api/v1/reports/jobs", s.jobsReport)
+	mux.HandleFunc("GET /api/v1/reports/repositories", s.repositoriesReport)
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// timeRange parses the from and to query params
+func timeRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be an RFC 3339 time, got %q", v)
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be an RFC 3339 time, got %q", v)
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
+}
+
+// query runs the query and scans every row with scan, writing the rows or the error as the response
+func query[T any](s *SQLite, w http.ResponseWriter, r *http.Request, scan func(rows *sql.Rows) (T, error), q string, args ...any) {
+	rows, err := s.db.QueryContext(r.Context(), q, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer rows.Close()
+
+	data := make([]T, 0)
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		data = append(data, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+type snapshot struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	VBRHost    string    `json:"vbr_host"`
+	VBRName    string    `json:"vbr_name"`
+	VBRVersion string    `json:"vbr_version"`
+}
+
+func (s *SQLite) snapshots(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive number, got %q", v))
+			return
+		}
+		limit = l
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (snapshot, error) {
+		var (
+			sn snapshot
+			t  int64
+		)
+		err := rows.Scan(&sn.ID, &t, &sn.VBRHost, &sn.VBRName, &sn.VBRVersion)
+		sn.Time = time.Unix(t, 0).UTC()
+		return sn, err
+	}, "SELECT id, time, vbr_host, vbr_name, vbr_version FROM snapshots ORDER BY time DESC, id DESC LIMIT ?", limit)
+}
+
+type session struct {
+	ID              string     `json:"id"`
+	JobID           string     `json:"job_id"`
+	Name            string     `json:"name"`
+	SessionType     string     `json:"session_type"`
+	State           string     `json:"state"`
+	Result          string     `json:"result"`
+	ResultMessage   string     `json:"result_message"`
+	CreationTime    time.Time  `json:"creation_time"`
+	EndTime         *time.Time `json:"end_time"`
+	DurationSeconds *float64   `json:"duration_seconds"`
+}
+
+func (s *SQLite) sessionHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (session, error) {
+		var (
+			ses     session
+			jobID   sql.NullString
+			created int64
+			end     sql.NullInt64
+		)
+
+		err := rows.Scan(&ses.ID, &jobID, &ses.Name, &ses.SessionType, &ses.State, &ses.Result, &ses.ResultMessage,
+			&created, &end, &ses.DurationSeconds)
+
+		ses.JobID = jobID.String
+		ses.CreationTime = time.Unix(created, 0).UTC()
+		if end.Valid {
+			t := time.Unix(end.Int64, 0).UTC()
+			ses.EndTime = &t
+		}
+
+		return ses, err
+	}, `SELECT id, job_id, name, session_type, state, result, result_message, creation_time, end_time, duration_seconds
+FROM sessions
+WHERE creation_time BETWEEN ? AND ? AND (? = '' OR name = ?)
+ORDER BY creation_time`, from.Unix(), to.Unix(), r.URL.Query().Get("name"), r.URL.Query().Get("name"))
+}
+
+type repositoryPoint struct {
+	Time          time.Time `json:"time"`
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	CapacityBytes float64   `json:"capacity_bytes"`
+	FreeBytes     float64   `json:"free_bytes"`
+	UsedBytes     float64   `json:"used_bytes"`
+	IsOnline      bool      `json:"is_online"`
+}
+
+func (s *SQLite) repositoryHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (repositoryPoint, error) {
+		var (
+			p repositoryPoint
+			t int64
+		)
+		err := rows.Scan(&t, &p.ID, &p.Name, &p.Type, &p.CapacityBytes, &p.FreeBytes, &p.UsedBytes, &p.IsOnline)
+		p.Time = time.Unix(t, 0).UTC()
+		return p, err
+	}, `SELECT s.time, r.id, r.name, r.type, r.capacity_bytes, r.free_bytes, r.used_bytes, r.is_online
+FROM repositories r JOIN snapshots s ON s.id = r.snapshot_id
+WHERE s.time BETWEEN ? AND ? AND (? = '' OR r.name = ?)
+ORDER BY s.time, r.name`, from.Unix(), to.Unix(), r.URL.Query().Get("name"), r.URL.Query().Get("name"))
+}
+
+type proxyPoint struct {
+	Time         time.Time `json:"time"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	HostName     string    `json:"host_name"`
+	MaxTaskCount int64     `json:"max_task_count"`
+	IsOnline     bool      `json:"is_online"`
+}
+
+func (s *SQLite) proxyHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (proxyPoint, error) {
+		var (
+			p proxyPoint
+			t int64
+		)
+		err := rows.Scan(&t, &p.ID, &p.Name, &p.Type, &p.HostName, &p.MaxTaskCount, &p.IsOnline)
+		p.Time = time.Unix(t, 0).UTC()
+		return p, err
+	}, `SELECT s.time, p.id, p.name, p.type, p.host_name, p.max_task_count, p.is_online
+FROM proxies p JOIN snapshots s ON s.id = p.snapshot_id
+WHERE s.time BETWEEN ? AND ? AND (? = '' OR p.name = ?)
+ORDER BY s.time, p.name`, from.Unix(), to.Unix(), r.URL.Query().Get("name"), r.URL.Query().Get("name"))
+}
+
+type backupObjectPoint struct {
+	Time               time.Time `json:"time"`
+	ID                 string    `json:"id"`
+	ObjectID           string    `json:"object_id"`
+	Name               string    `json:"name"`
+	Type               string    `json:"type"`
+	Platform           string    `json:"platform"`
+	Path               string    `json:"path"`
+	RestorePointsCount int64     `json:"restore_points_count"`
+}
+
+func (s *SQLite) backupObjectHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (backupObjectPoint, error) {
+		var (
+			p backupObjectPoint
+			t int64
+		)
+		err := rows.Scan(&t, &p.ID, &p.ObjectID, &p.Name, &p.Type, &p.Platform, &p.Path, &p.RestorePointsCount)
+		p.Time = time.Unix(t, 0).UTC()
+		return p, err
+	}, `SELECT s.time, b.id, b.object_id, b.name, b.type, b.platform, b.path, b.restore_points_count
+FROM backup_objects b JOIN snapshots s ON s.id = b.snapshot_id
+WHERE s.time BETWEEN ? AND ? AND (? = '' OR b.name = ?)
+ORDER BY s.time, b.name`, from.Unix(), to.Unix(), r.URL.Query().Get("name"), r.URL.Query().Get("name"))
+}
+
+type jobReport struct {
+	Name                string     `json:"name"`
+	SessionType         string     `json:"session_type"`
+	Sessions            int64      `json:"sessions"`
+	Success             int64      `json:"success"`
+	Warning             int64      `json:"warning"`
+	Failed              int64      `json:"failed"`
+	SuccessRate         float64    `json:"success_rate"`
+	AvgDurationSeconds  *float64   `json:"avg_duration_seconds"`
+	LastResult          string     `json:"last_result"`
+	LastSessionCreation time.Time  `json:"last_session_creation_time"`
+	LastSessionEnd      *time.Time `json:"last_session_end_time"`
+}
+
+// jobsReport summarizes the sessions of every job in the time range
+func (s *SQLite) jobsReport(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (jobReport, error) {
+		var (
+			j       jobReport
+			created int64
+			end     sql.NullInt64
+		)
+
+		err := rows.Scan(&j.Name, &j.SessionType, &j.Sessions, &j.Success, &j.Warning, &j.Failed, &j.AvgDurationSeconds,
+			&j.LastResult, &created, &end)
+
+		if finished := j.Success + j.Warning + j.Failed; finished > 0 {
+			j.SuccessRate = float64(j.Success) / float64(finished) * 100
+		}
+
+		j.LastSessionCreation = time.Unix(created, 0).UTC()
+		if end.Valid {
+			t := time.Unix(end.Int64, 0).UTC()
+			j.LastSessionEnd = &t
+		}
+
+		return j, err
+	}, `WITH ranged AS (
+	SELECT * FROM sessions WHERE creation_time BETWEEN ? AND ?
+), last AS (
+	SELECT name, session_type, result, creation_time, end_time,
+		row_number() OVER (PARTITION BY name, session_type ORDER BY creation_time DESC) AS n
+	FROM ranged
+)
+SELECT r.name, r.session_type, count(*),
+	sum(r.result = 'Success'), sum(r.result = 'Warning'), sum(r.result = 'Failed'),
+	avg(r.duration_seconds), l.result, l.creation_time, l.end_time
+FROM ranged r JOIN last l ON l.name = r.name AND l.session_type = r.session_type AND l.n = 1
+GROUP BY r.name, r.session_type
+ORDER BY r.name, r.session_type`, from.Unix(), to.Unix())
+}
+
+type repositoryReport struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	CapacityBytes   float64 `json:"capacity_bytes"`
+	UsedBytes       float64 `json:"used_bytes"`
+	FreePercent     float64 `json:"free_percent"`
+	UsedGrowthBytes float64 `json:"used_growth_bytes"`
+}
+
+// repositoriesReport returns the last capacity of every repository and the growth of its used space in the time range
+func (s *SQLite) repositoriesReport(w http.ResponseWriter, r *http.Request) {
+	from, to, err := timeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query(s, w, r, func(rows *sql.Rows) (repositoryReport, error) {
+		var (
+			rep   repositoryReport
+			first float64
+			free  float64
+		)
+
+		err := rows.Scan(&rep.ID, &rep.Name, &rep.Type, &rep.CapacityBytes, &free, &rep.UsedBytes, &first)
+
+		if rep.CapacityBytes > 0 {
+			rep.FreePercent = free / rep.CapacityBytes * 100
+		}
+		rep.UsedGrowthBytes = rep.UsedBytes - first
+
+		return rep, err
+	}, `WITH ranged AS (
+	SELECT r.*, s.time,
+		row_number() OVER (PARTITION BY r.id ORDER BY s.time DESC) AS last_n,
+		row_number() OVER (PARTITION BY r.id ORDER BY s.time) AS first_n
+	FROM repositories r JOIN snapshots s ON s.id = r.snapshot_id
+	WHERE s.time BETWEEN ? AND ?
+)
+SELECT l.id, l.name, l.type, l.capacity_bytes, l.free_bytes, l.used_bytes, f.used_bytes
+FROM ranged l JOIN ranged f ON f.id = l.id AND f.first_n = 1
+WHERE l.last_n = 1
+ORDER BY l.name`, from.Unix(), to.Unix())
+}
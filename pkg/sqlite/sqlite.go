@@ -0,0 +1,218 @@
+// Package sqlite stores every collection snapshot in an embedded SQLite database and serves its history over http.
+// The pure-Go driver needs no cgo, so govein stays a single binary.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	_ "modernc.org/sqlite"
+)
+
+const defaultRetentionDays = 30
+
+type SQLite struct {
+	ctx context.Context
+	log *slog.Logger
+	db  *sql.DB
+
+	mu   sync.RWMutex
+	conf config.Config
+}
+
+// New opens the database, creating it if it does not exist, and migrates its tables
+func New(ctx context.Context, conf config.Config, log *slog.Logger) (*SQLite, error) {
+	if conf.SQLite == nil {
+		return nil, fmt.Errorf("sqlite is not configured")
+	}
+
+	pragmas := url.Values{"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)"}}
+
+	db, err := sql.Open("sqlite", "file:"+conf.SQLite.Path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("could not open sqlite database: %v", err)
+	}
+
+	// a single connection serializes the writes of the collector and the reads of the http endpoints
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{
+		ctx:  ctx,
+		log:  log,
+		db:   db,
+		conf: conf,
+	}
+
+	if err = s.migrate(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	log.Info("SQLite history store opened", "path", conf.SQLite.Path, "retention_days", s.retentionDays())
+
+	return s, nil
+}
+
+func (s *SQLite) Name() string {
+	return config.SinkSQLite
+}
+
+// SetConfig swaps the settings used when storing data, such as the retention, the path needs a new sink instead
+func (s *SQLite) SetConfig(conf config.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conf = conf
+
+	return nil
+}
+
+func (s *SQLite) retentionDays() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.conf.SQLite == nil || s.conf.SQLite.RetentionDays <= 0 {
+		return defaultRetentionDays
+	}
+
+	return s.conf.SQLite.RetentionDays
+}
+
+func (s *SQLite) Ping() error {
+	return s.db.PingContext(s.ctx)
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// migrations are applied in order and never changed once released, add a new one instead.
+// The applied version is kept in the user_version pragma.
+var migrations = []string{
+	`
+CREATE TABLE snapshots (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	time        INTEGER NOT NULL,
+	vbr_host    TEXT NOT NULL,
+	vbr_name    TEXT NOT NULL,
+	vbr_version TEXT NOT NULL
+);
+
+CREATE INDEX snapshots_time_idx ON snapshots (time);
+
+CREATE TABLE sessions (
+	id               TEXT PRIMARY KEY,
+	vbr_host         TEXT NOT NULL,
+	job_id           TEXT,
+	name             TEXT NOT NULL,
+	session_type     TEXT NOT NULL,
+	state            TEXT NOT NULL,
+	result           TEXT NOT NULL,
+	result_message   TEXT NOT NULL,
+	creation_time    INTEGER NOT NULL,
+	end_time         INTEGER,
+	duration_seconds REAL
+);
+
+CREATE INDEX sessions_creation_time_idx ON sessions (creation_time);
+
+CREATE TABLE managed_servers (
+	snapshot_id INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	id          TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	status      TEXT NOT NULL
+);
+
+CREATE INDEX managed_servers_snapshot_id_idx ON managed_servers (snapshot_id);
+
+CREATE TABLE repositories (
+	snapshot_id    INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	id             TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	type           TEXT NOT NULL,
+	path           TEXT NOT NULL,
+	capacity_bytes REAL NOT NULL,
+	free_bytes     REAL NOT NULL,
+	used_bytes     REAL NOT NULL,
+	is_online      INTEGER NOT NULL
+);
+
+CREATE INDEX repositories_snapshot_id_idx ON repositories (snapshot_id);
+
+CREATE TABLE proxies (
+	snapshot_id    INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	id             TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	type           TEXT NOT NULL,
+	host_name      TEXT NOT NULL,
+	max_task_count INTEGER NOT NULL,
+	is_online      INTEGER NOT NULL
+);
+
+CREATE INDEX proxies_snapshot_id_idx ON proxies (snapshot_id);
+
+CREATE TABLE backup_objects (
+	snapshot_id          INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	id                   TEXT NOT NULL,
+	object_id            TEXT NOT NULL,
+	name                 TEXT NOT NULL,
+	type                 TEXT NOT NULL,
+	platform             TEXT NOT NULL,
+	path                 TEXT NOT NULL,
+	restore_points_count INTEGER NOT NULL
+);
+
+CREATE INDEX backup_objects_snapshot_id_idx ON backup_objects (snapshot_id);
+`,
+}
+
+func (s *SQLite) migrate(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("could not read sqlite schema version: %v", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		s.log.Info("Migrating sqlite schema", "version", i+1)
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("could not begin migration: %v", err)
+		}
+
+		if _, err = tx.ExecContext(ctx, migrations[i]); err == nil {
+			// pragmas take no parameters
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		}
+
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not apply sqlite migration %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// unix converts a time to the unix seconds stored in the database, zero times are stored as null
+func unix(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+
+	u := t.Unix()
+
+	return &u
+}
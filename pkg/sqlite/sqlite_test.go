@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/sink/sinktest"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// recent returns the collected data with the fixture sessions moved to the last hours, so they are within the retention
+func recent(t *testing.T) (veeam.Veeam, config.Config) {
+	t.Helper()
+
+	v, conf := sinktest.Collected(t)
+
+	var latest time.Time
+	for _, ses := range v.Sessions.Data {
+		for _, ts := range []time.Time{ses.CreationTime, ses.EndTime} {
+			if ts.After(latest) {
+				latest = ts
+			}
+		}
+	}
+
+	shift := time.Since(latest) - time.Hour
+	for i := range v.Sessions.Data {
+		v.Sessions.Data[i].CreationTime = v.Sessions.Data[i].CreationTime.Add(shift)
+		if !v.Sessions.Data[i].EndTime.IsZero() {
+			v.Sessions.Data[i].EndTime = v.Sessions.Data[i].EndTime.Add(shift)
+		}
+	}
+
+	return v, conf
+}
+
+func newSQLite(t *testing.T, conf config.Config) *SQLite {
+	t.Helper()
+
+	conf.SQLite = &config.SQLite{Path: filepath.Join(t.TempDir(), "govein.db")}
+
+	s, err := New(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+func count(t *testing.T, s *SQLite, table string) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestStore(t *testing.T) {
+	v, conf := recent(t)
+	s := newSQLite(t, conf)
+
+	// stored twice, sessions are upserted and everything else is kept per snapshot
+	for range 2 {
+		if err := s.Store(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var repositories int
+	for _, r := range v.Repositories {
+		repositories += len(r.Data)
+	}
+
+	want := map[string]int{
+		"snapshots":       2,
+		"sessions":        len(v.Sessions.Data),
+		"managed_servers": 2 * len(v.ManagedSevers.Data),
+		"repositories":    2 * repositories,
+		"proxies":         2 * len(v.Proxies.Data),
+		"backup_objects":  2 * len(v.BackupObjects.Data),
+	}
+
+	for table, n := range want {
+		if got := count(t, s, table); got != n {
+			t.Errorf("expected %d rows in %s, got %d", n, table, got)
+		}
+	}
+
+	// migrating an up to date database applies nothing
+	if err := s.migrate(context.Background()); err != nil {
+		t.Errorf("expected migrations to be idempotent, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	v, conf := recent(t)
+	s := newSQLite(t, conf)
+
+	if err := s.Store(v); err != nil {
+		t.Fatal(err)
+	}
+
+	// the snapshot rows are deleted in cascade, which needs the foreign_keys pragma of the dsn
+	if err := s.prune(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"snapshots", "sessions", "managed_servers", "repositories", "proxies", "backup_objects"} {
+		if n := count(t, s, table); n != 0 {
+			t.Errorf("expected %s to be pruned, got %d rows", table, n)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	v, conf := recent(t)
+	s := newSQLite(t, conf)
+
+	if err := s.Store(v); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+
+	get := func(path string, status int) []map[string]any {
+		t.Helper()
+
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Fatalf("%s: expected status %d, got %d", path, status, resp.StatusCode)
+		}
+
+		var body struct {
+			Data []map[string]any `json:"data"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		return body.Data
+	}
+
+	if data := get("/api/v1/snapshots", http.StatusOK); len(data) != 1 || data[0]["vbr_host"] != conf.Veeam.Host {
+		t.Errorf("unexpected snapshots %v", data)
+	}
+
+	if data := get("/api/v1/history/sessions", http.StatusOK); len(data) != len(v.Sessions.Data) {
+		t.Errorf("expected %d sessions, got %d", len(v.Sessions.Data), len(data))
+	}
+
+	if data := get("/api/v1/history/backup_objects", http.StatusOK); len(data) != len(v.BackupObjects.Data) {
+		t.Errorf("expected %d backup objects, got %d", len(v.BackupObjects.Data), len(data))
+	}
+
+	name := v.Proxies.Data[0].Name
+	if data := get("/api/v1/history/proxies?name="+name, http.StatusOK); len(data) != 1 || data[0]["name"] != name {
+		t.Errorf("expected the history of proxy %q, got %v", name, data)
+	}
+
+	if data := get("/api/v1/reports/jobs", http.StatusOK); len(data) == 0 || data[0]["sessions"].(float64) == 0 {
+		t.Errorf("unexpected jobs report %v", data)
+	}
+
+	if data := get("/api/v1/reports/repositories", http.StatusOK); len(data) == 0 || data[0]["used_growth_bytes"].(float64) != 0 {
+		t.Errorf("expected no growth within a single snapshot, got %v", data)
+	}
+
+	// snapshots stored later are outside the range
+	if data := get("/api/v1/history/repositories?to=2000-01-01T00:00:00Z", http.StatusOK); len(data) != 0 {
+		t.Errorf("expected no repositories before the snapshot, got %v", data)
+	}
+
+	get("/api/v1/history/repositories?from=yesterday", http.StatusBadRequest)
+	get("/api/v1/snapshots?limit=-1", http.StatusBadRequest)
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/veeam"
+)
+
+// Store saves the collected data as a new snapshot and prunes the snapshots past the retention
+func (s *SQLite) Store(v veeam.Veeam) error {
+	s.log.Info("Storing snapshot into sqlite")
+
+	s.mu.RLock()
+	host := s.conf.Veeam.Host
+	s.mu.RUnlock()
+
+	now := time.Now()
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin sqlite transaction: %v", err)
+	}
+
+	if err = store(s.ctx, tx, host, v, now); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("could not store snapshot: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit snapshot: %v", err)
+	}
+
+	return s.prune(now.AddDate(0, 0, -s.retentionDays()))
+}
+
+func store(ctx context.Context, tx *sql.Tx, host string, v veeam.Veeam, now time.Time) error {
+	res, err := tx.ExecContext(ctx, "INSERT INTO snapshots (time, vbr_host, vbr_name, vbr_version) VALUES (?, ?, ?, ?)",
+		now.Unix(), host, v.ServerInfo.Name, v.ServerInfo.BuildVersion)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, ses := range v.Sessions.Data {
+		var duration *float64
+		if !ses.EndTime.IsZero() {
+			d := ses.EndTime.Sub(ses.CreationTime).Seconds()
+			duration = &d
+		}
+
+		if _, err = tx.ExecContext(ctx, `INSERT INTO sessions (id, vbr_host, job_id, name, session_type, state, result, result_message,
+	creation_time, end_time, duration_seconds)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET state = excluded.state, result = excluded.result, result_message = excluded.result_message,
+	end_time = excluded.end_time, duration_seconds = excluded.duration_seconds`,
+			ses.ID, host, ses.JobID, ses.Name, ses.SessionType, ses.State, ses.Result.Result, ses.Result.Message,
+			ses.CreationTime.Unix(), unix(ses.EndTime), duration); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range v.ManagedSevers.Data {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO managed_servers (snapshot_id, id, name, type, status) VALUES (?, ?, ?, ?, ?)",
+			id, m.ID, m.Name, m.Type, m.Status); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range v.Repositories {
+		for _, rd := range r.Data {
+			if _, err = tx.ExecContext(ctx, `INSERT INTO repositories (snapshot_id, id, name, type, path, capacity_bytes, free_bytes, used_bytes, is_online)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, rd.ID, rd.Name, rd.Type, strings.TrimRight(rd.Path, "\\"),
+				rd.CapacityGB*1024*1024*1024, rd.FreeGB*1024*1024*1024, rd.UsedSpaceGB*1024*1024*1024, rd.IsOnline); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range v.Proxies.Data {
+		hostName := p.Server.HostID
+		if h, ok := v.ManagedSevers.ServerByID(p.Server.HostID); ok {
+			hostName = h.Name
+		}
+
+		var state veeam.ProxyStatesData
+		for _, st := range v.ProxyStates.Data {
+			if st.ID == p.ID {
+				state = st
+				break
+			}
+		}
+
+		if _, err = tx.ExecContext(ctx, `INSERT INTO proxies (snapshot_id, id, name, type, host_name, max_task_count, is_online)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, p.ID, p.Name, p.Type, hostName, p.Server.MaxTaskCount, state.IsOnline); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range v.BackupObjects.Data {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO backup_objects (snapshot_id, id, object_id, name, type, platform, path, restore_points_count)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, b.ID, b.ObjectID, b.Name, string(b.Type), string(b.PlatformName), b.Path, b.RestorePointsCount); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prune deletes the snapshots and sessions older than the cutoff, the rows of the snapshots are deleted in cascade
+func (s *SQLite) prune(cutoff time.Time) error {
+	res, err := s.db.ExecContext(s.ctx, "DELETE FROM snapshots WHERE time < ?", cutoff.Unix())
+	if err != nil {
+		return fmt.Errorf("could not prune snapshots: %v", err)
+	}
+
+	if _, err = s.db.ExecContext(s.ctx, "DELETE FROM sessions WHERE creation_time < ?", cutoff.Unix()); err != nil {
+		return fmt.Errorf("could not prune sessions: %v", err)
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		s.log.Debug("Pruned sqlite snapshots", "count", n, "before", cutoff.Format(time.RFC3339))
+	}
+
+	return nil
+}
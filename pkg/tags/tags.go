@@ -0,0 +1,101 @@
+// Package tags enriches the stored data with custom tags, such as site, tenant or environment.
+// Static tags are added to every point, rule-based tags to the points of the objects matching the rule.
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+)
+
+// Rule adds its tags to the objects whose field matches the glob or regex, see filter.Rule for the matching semantics
+type Rule struct {
+	Field filter.Field      `yaml:"field"`
+	Glob  string            `yaml:"glob,omitempty"`
+	Regex string            `yaml:"regex,omitempty"`
+	Tags  map[string]string `yaml:"tags"`
+}
+
+// Validate checks the rule matcher and its tags
+func (r Rule) Validate() error {
+	if _, err := (filter.Rule{Field: r.Field, Glob: r.Glob, Regex: r.Regex}).Compile(); err != nil {
+		return err
+	}
+
+	if len(r.Tags) == 0 {
+		return errors.New("tags must be set")
+	}
+
+	return validateKeys(r.Tags)
+}
+
+func validateKeys(tags map[string]string) error {
+	for k := range tags {
+		if k == "" {
+			return errors.New("tag names can not be empty")
+		}
+	}
+
+	return nil
+}
+
+type rule struct {
+	field filter.Field
+	re    *regexp.Regexp
+	tags  map[string]string
+}
+
+// Tagger returns the custom tags of the stored objects. A nil tagger returns no tags.
+type Tagger struct {
+	static map[string]string
+	rules  []rule
+}
+
+func New(static map[string]string, rules []Rule) (*Tagger, error) {
+	if err := validateKeys(static); err != nil {
+		return nil, fmt.Errorf("invalid static tags: %v", err)
+	}
+
+	t := &Tagger{static: static}
+
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid tag rule %d: %v", i, err)
+		}
+
+		re, _ := filter.Rule{Field: r.Field, Glob: r.Glob, Regex: r.Regex}.Compile()
+		t.rules = append(t.rules, rule{field: r.Field, re: re, tags: r.Tags})
+	}
+
+	return t, nil
+}
+
+// Tags returns the static tags merged with the tags of every rule matching the object,
+// later rules override earlier ones and rules override static tags
+func (t *Tagger) Tags(o filter.Object) map[string]string {
+	if t == nil {
+		return nil
+	}
+
+	tags := make(map[string]string, len(t.static))
+	for k, v := range t.static {
+		tags[k] = v
+	}
+
+	for _, r := range t.rules {
+		for _, value := range o.Values(r.field) {
+			if !r.re.MatchString(value) {
+				continue
+			}
+
+			for k, v := range r.tags {
+				tags[k] = v
+			}
+			break
+		}
+	}
+
+	return tags
+}
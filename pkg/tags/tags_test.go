@@ -0,0 +1,48 @@
+package tags
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+)
+
+func TestTags(t *testing.T) {
+	tagger, err := New(map[string]string{"site": "ams", "environment": "prod"}, []Rule{
+		{Field: filter.JobName, Glob: "acme-*", Tags: map[string]string{"tenant": "acme"}},
+		{Field: filter.Repository, Regex: "^fra-", Tags: map[string]string{"site": "fra"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		object filter.Object
+		want   map[string]string
+	}{
+		{nil, map[string]string{"site": "ams", "environment": "prod"}},
+		{filter.Object{filter.JobName: "ACME-daily"}, map[string]string{"site": "ams", "environment": "prod", "tenant": "acme"}},
+		// rules override static tags
+		{filter.Object{filter.Repository: "fra-repo01"}, map[string]string{"site": "fra", "environment": "prod"}},
+	}
+
+	for _, c := range cases {
+		if got := tagger.Tags(c.object); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: expected %v, got %v", c.object, c.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []Rule{
+		{Field: filter.JobName, Glob: "*"},
+		{Field: filter.JobName, Glob: "*", Tags: map[string]string{"": "x"}},
+		{Field: "tenant", Glob: "*", Tags: map[string]string{"tenant": "x"}},
+	}
+
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("%+v: expected validation error", r)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package veeam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
+)
+
+type TaskSessions struct {
+	Data       []TaskSessionsData `json:"data"`
+	Pagination Pagination         `json:"pagination"`
+}
+
+// TaskSessionsData is the session of a single object processed by a job session
+type TaskSessionsData struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	SessionID    string    `json:"sessionId"`
+	SessionType  string    `json:"sessionType"`
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	CreationTime time.Time `json:"creationTime"`
+	EndTime      time.Time `json:"endTime"`
+	Result       struct {
+		Result     string `json:"result"`
+		Message    string `json:"message"`
+		IsCanceled bool   `json:"isCanceled"`
+	} `json:"result"`
+}
+
+// SessionsPage returns a page of the sessions created in [from, to), oldest first.
+// The pagination is the one of the api, so filtered out sessions still count towards it.
+func (v *Veeam) SessionsPage(from, to time.Time, skip, limit int) (Sessions, error) {
+	var ses Sessions
+	err := v.authorized(func() error {
+		var err error
+		ses, err = v.sessionsPage(from, to, skip, limit)
+		return err
+	})
+
+	return ses, err
+}
+
+func (v *Veeam) sessionsPage(from, to time.Time, skip, limit int) (Sessions, error) {
+	s, l := int32(skip), int32(limit)
+	orderColumn := client.ESessionsFiltersOrderColumnCreationTime
+	asc := true
+
+	resp, err := v.cl.GetAllSessionsWithResponse(v.ctx, &client.GetAllSessionsParams{
+		Skip:                &s,
+		Limit:               &l,
+		OrderColumn:         &orderColumn,
+		OrderAsc:            &asc,
+		CreatedAfterFilter:  &from,
+		CreatedBeforeFilter: &to,
+		XApiVersion:         v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return Sessions{}, fmt.Errorf("could not get sessions: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return Sessions{}, fmt.Errorf("could not get sessions: %w", err)
+	}
+
+	var ses Sessions
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&ses); err != nil {
+		return Sessions{}, fmt.Errorf("could not parse sessions: %v", err)
+	}
+
+	ses.Data = filter.Apply(v.filter, ses.Data, SessionsData.FilterObject)
+
+	return ses, nil
+}
+
+// TaskSessionsPage returns a page of the task sessions created in [from, to), oldest first.
+// The pagination is the one of the api, so filtered out task sessions still count towards it.
+func (v *Veeam) TaskSessionsPage(from, to time.Time, skip, limit int) (TaskSessions, error) {
+	var ts TaskSessions
+	err := v.authorized(func() error {
+		var err error
+		ts, err = v.taskSessionsPage(from, to, skip, limit)
+		return err
+	})
+
+	return ts, err
+}
+
+func (v *Veeam) taskSessionsPage(from, to time.Time, skip, limit int) (TaskSessions, error) {
+	q := url.Values{
+		"skip":                {strconv.Itoa(skip)},
+		"limit":               {strconv.Itoa(limit)},
+		"orderColumn":         {"CreationTime"},
+		"orderAsc":            {"true"},
+		"createdAfterFilter":  {from.UTC().Format(time.RFC3339)},
+		"createdBeforeFilter": {to.UTC().Format(time.RFC3339)},
+	}
+
+	var ts TaskSessions
+	if err := v.getJSON("/api/v1/taskSessions", q, &ts); err != nil {
+		return TaskSessions{}, fmt.Errorf("could not get task sessions: %w", err)
+	}
+
+	ts.Data = filter.Apply(v.filter, ts.Data, TaskSessionsData.FilterObject)
+
+	return ts, nil
+}
@@ -0,0 +1,446 @@
+package veeam
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const bundleVersion = 1
+
+// Bundle is a set of raw veeam api responses, captured from a live server and served back in replay mode
+type Bundle struct {
+	Version    int                `json:"version"`
+	APIVersion string             `json:"apiVersion"`
+	CapturedAt time.Time          `json:"capturedAt"`
+	Anonymized bool               `json:"anonymized"`
+	Responses  []RecordedResponse `json:"responses"`
+}
+
+type RecordedResponse struct {
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Query       string          `json:"query,omitempty"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+}
+
+func (r RecordedResponse) key() string {
+	return requestKey(r.Method, r.Path, r.Query)
+}
+
+func requestKey(method, path, query string) string {
+	// canonical query, parameters sorted by key
+	q, _ := url.ParseQuery(query)
+	return method + " " + path + "?" + q.Encode()
+}
+
+// Save writes the bundle as gzipped json
+func (b *Bundle) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create bundle file: %v", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+
+	enc := json.NewEncoder(gz)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(b); err != nil {
+		return fmt.Errorf("could not encode bundle: %v", err)
+	}
+
+	return gz.Close()
+}
+
+// LoadBundle reads a bundle written by Save, plain json bundles are accepted as well
+func LoadBundle(path string) (*Bundle, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read bundle file: %v", err)
+	}
+
+	var r io.Reader = bytes.NewReader(raw)
+	if gz, err := gzip.NewReader(bytes.NewReader(raw)); err == nil {
+		r = gz
+	}
+
+	var b Bundle
+	if err = json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("could not parse bundle: %v", err)
+	}
+
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	return &b, nil
+}
+
+// Recorder is an http.RoundTripper which records the api responses passing through it.
+// Authentication requests are never recorded.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	responses []RecordedResponse
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rec.next.RoundTrip(req)
+	if err != nil || strings.HasPrefix(req.URL.Path, "/api/oauth2/") {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := RecordedResponse{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       req.URL.RawQuery,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+
+	if json.Valid(body) {
+		recorded.Body = body
+	}
+
+	rec.mu.Lock()
+	rec.responses = append(rec.responses, recorded)
+	rec.mu.Unlock()
+
+	return resp, nil
+}
+
+// Bundle returns the recorded responses, the last response wins if a request was repeated
+func (rec *Recorder) Bundle(apiVersion string) *Bundle {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	b := &Bundle{
+		Version:    bundleVersion,
+		APIVersion: apiVersion,
+		CapturedAt: time.Now().UTC(),
+	}
+
+	index := make(map[string]int)
+	for _, r := range rec.responses {
+		if i, ok := index[r.key()]; ok {
+			b.Responses[i] = r
+			continue
+		}
+
+		index[r.key()] = len(b.Responses)
+		b.Responses = append(b.Responses, r)
+	}
+
+	return b
+}
+
+// ReplayTransport is an http.RoundTripper serving the responses of a bundle in place of the network
+type ReplayTransport struct {
+	responses map[string]RecordedResponse
+}
+
+func NewReplayTransport(b *Bundle) *ReplayTransport {
+	rt := &ReplayTransport{responses: make(map[string]RecordedResponse, len(b.Responses))}
+	for _, r := range b.Responses {
+		rt.responses[r.key()] = r
+	}
+
+	return rt
+}
+
+func (rt *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	if req.URL.Path == "/api/oauth2/token" {
+		return replayResponse(req, http.StatusOK, "application/json",
+			[]byte(`{"access_token":"replay","token_type":"bearer","refresh_token":"replay","expires_in":900}`)), nil
+	}
+
+	r, ok := rt.responses[requestKey(req.Method, req.URL.Path, req.URL.RawQuery)]
+	if !ok {
+		body, _ := json.Marshal(map[string]any{
+			"errorCode":  "NotFound",
+			"message":    fmt.Sprintf("%s %s is not part of the replay bundle", req.Method, req.URL.RequestURI()),
+			"resourceId": nil,
+		})
+
+		return replayResponse(req, http.StatusNotFound, "application/json", body), nil
+	}
+
+	return replayResponse(req, r.Status, r.ContentType, r.Body), nil
+}
+
+func replayResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	h := make(http.Header)
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// keys holding names, hosts, paths and other identifying values
+var sensitiveKeys = map[string]struct{}{
+	"name":             {},
+	"hostName":         {},
+	"description":      {},
+	"path":             {},
+	"sharePath":        {},
+	"username":         {},
+	"friendlyName":     {},
+	"subject":          {},
+	"issuedBy":         {},
+	"issuedTo":         {},
+	"thumbprint":       {},
+	"serialNumber":     {},
+	"objectId":         {},
+	"cacheFolder":      {},
+	"writeCacheFolder": {},
+	"sqlServerEdition": {},
+}
+
+// keys holding free text, where identifying values are replaced in place
+var freeTextKeys = map[string]struct{}{
+	"message": {},
+}
+
+var (
+	uuidRe     = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hostnameRe = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+$`)
+)
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
+
+// anonymizer consistently hashes identifying values, the same value is always replaced the same way.
+// A random salt is used, so hashes can not be reversed by hashing guessed values.
+type anonymizer struct {
+	salt     []byte
+	ns       uuid.UUID
+	replaced map[string]string
+	// replaced values, longest first, used for free text
+	originals []string
+}
+
+func newAnonymizer() (*anonymizer, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &anonymizer{
+		salt:     salt,
+		ns:       uuid.NewSHA1(uuid.NameSpaceOID, salt),
+		replaced: make(map[string]string),
+	}, nil
+}
+
+func (a *anonymizer) hash(s string) string {
+	m := hmac.New(sha256.New, a.salt)
+	m.Write([]byte(s))
+	return hex.EncodeToString(m.Sum(nil))[:10]
+}
+
+func (a *anonymizer) uuid(s string) string {
+	if s == uuid.Nil.String() {
+		return s
+	}
+
+	return uuid.NewSHA1(a.ns, []byte(strings.ToLower(s))).String()
+}
+
+// value replaces an identifying value, hostnames keep their shape
+func (a *anonymizer) value(s string) string {
+	if s == "" {
+		return s
+	}
+
+	if r, ok := a.replaced[s]; ok {
+		return r
+	}
+
+	var r string
+	switch {
+	case isUUID(s):
+		r = a.uuid(s)
+	case hostnameRe.MatchString(s):
+		r = "host-" + a.hash(s) + ".invalid"
+	default:
+		r = "anon-" + a.hash(s)
+	}
+
+	a.replaced[s] = r
+
+	return r
+}
+
+// uuids replaces every uuid found in the string
+func (a *anonymizer) uuids(s string) string {
+	return uuidRe.ReplaceAllStringFunc(s, a.uuid)
+}
+
+// walk anonymizes a decoded json document, key is the object key holding the value
+func (a *anonymizer) walk(key string, v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = a.walk(k, val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = a.walk(key, val)
+		}
+		return t
+	case string:
+		if _, ok := sensitiveKeys[key]; ok {
+			return a.value(t)
+		}
+		return a.uuids(t)
+	default:
+		return v
+	}
+}
+
+// text replaces the identifying values already seen anywhere in free text
+func (a *anonymizer) text(key string, v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = a.text(k, val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = a.text(key, val)
+		}
+		return t
+	case string:
+		if _, ok := freeTextKeys[key]; !ok {
+			return t
+		}
+		for _, orig := range a.originals {
+			t = strings.ReplaceAll(t, orig, a.replaced[orig])
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// Anonymize consistently hashes the hostnames, names, paths and ids in the bundle,
+// including the ones used in request paths and query parameters
+func (b *Bundle) Anonymize() error {
+	if b.Anonymized {
+		return nil
+	}
+
+	a, err := newAnonymizer()
+	if err != nil {
+		return fmt.Errorf("could not create anonymizer: %v", err)
+	}
+
+	docs := make([]any, len(b.Responses))
+	for i, r := range b.Responses {
+		if len(r.Body) == 0 {
+			continue
+		}
+
+		if err = json.Unmarshal(r.Body, &docs[i]); err != nil {
+			return fmt.Errorf("could not parse response of %s: %v", r.Path, err)
+		}
+
+		docs[i] = a.walk("", docs[i])
+	}
+
+	for orig := range a.replaced {
+		if len(orig) >= 4 {
+			a.originals = append(a.originals, orig)
+		}
+	}
+
+	sort.Slice(a.originals, func(i, j int) bool {
+		return len(a.originals[i]) > len(a.originals[j])
+	})
+
+	for i, r := range b.Responses {
+		if docs[i] != nil {
+			if r.Body, err = json.Marshal(a.text("", docs[i])); err != nil {
+				return fmt.Errorf("could not encode response of %s: %v", r.Path, err)
+			}
+		}
+
+		segments := strings.Split(r.Path, "/")
+		for j, s := range segments {
+			if _, ok := a.replaced[s]; ok || isUUID(s) {
+				segments[j] = a.value(s)
+			}
+		}
+		r.Path = strings.Join(segments, "/")
+
+		q, err := url.ParseQuery(r.Query)
+		if err != nil {
+			return fmt.Errorf("could not parse query of %s: %v", r.Path, err)
+		}
+
+		for k, vals := range q {
+			for j, val := range vals {
+				if _, ok := a.replaced[val]; ok || isUUID(val) || strings.HasPrefix(k, "name") {
+					vals[j] = a.value(val)
+				}
+			}
+		}
+		r.Query = q.Encode()
+
+		b.Responses[i] = r
+	}
+
+	b.Anonymized = true
+
+	return nil
+}
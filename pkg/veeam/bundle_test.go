@@ -0,0 +1,86 @@
+package veeam
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+)
+
+func TestCaptureAndReplay(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rec := NewRecorder()
+
+	v, err := NewVeeam(context.Background(), srv.Config(), log, WithRecorder(rec))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	if err = v.Ping(); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+
+	if err = v.Collect(); err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+
+	b := rec.Bundle(veeamtest.APIVersion)
+	if err = b.Anonymize(); err != nil {
+		t.Fatalf("could not anonymize bundle: %v", err)
+	}
+
+	raw, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, leaked := range []string{"vcenter.lab.local", "vbr01.lab.local", "web01", "svc_veeam", "7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9a01", "access_token"} {
+		if strings.Contains(string(raw), leaked) {
+			t.Errorf("anonymized bundle contains %q", leaked)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "bundle.json.gz")
+	if err = b.Save(file); err != nil {
+		t.Fatalf("could not save bundle: %v", err)
+	}
+
+	conf := srv.Config()
+	conf.Veeam.ReplayFile = file
+	srv.Close()
+
+	replay, err := NewVeeam(context.Background(), conf, log)
+	if err != nil {
+		t.Fatalf("could not create replay client: %v", err)
+	}
+
+	if err = replay.Ping(); err != nil {
+		t.Fatalf("replay ping failed: %v", err)
+	}
+
+	if err = replay.Collect(); err != nil {
+		t.Fatalf("replay collect failed: %v", err)
+	}
+
+	if len(replay.Sessions.Data) != len(v.Sessions.Data) || len(replay.Repositories) != len(v.Repositories) {
+		t.Errorf("replayed data does not match the captured data")
+	}
+
+	// hashing is consistent, so the inventory still cross-references with jobs and backup objects
+	if got, want := replay.Protection(), v.Protection(); got.Protected != want.Protected || got.Total != want.Total {
+		t.Errorf("replayed protection %+v does not match captured %+v", got, want)
+	}
+
+	if replay.ManagedSevers.Data[0].Name == v.ManagedSevers.Data[0].Name {
+		t.Errorf("managed server name was not anonymized")
+	}
+}
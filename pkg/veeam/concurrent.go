@@ -0,0 +1,28 @@
+package veeam
+
+import (
+	"errors"
+	"sync"
+)
+
+// forEach calls fn for every index up to n at once. The requests in flight are bounded by veeam.concurrency
+// in the transport, which is shared by every collector, so nested calls do not multiply the limit.
+// Every call runs, the errors of the failed ones are joined in index order.
+func (v *Veeam) forEach(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = fn(i)
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package veeam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
+)
+
+type ConfigBackup struct {
+	IsEnabled            bool                    `json:"isEnabled"`
+	BackupRepositoryID   string                  `json:"backupRepositoryId"`
+	RestorePointsToKeep  int64                   `json:"restorePointsToKeep"`
+	Encryption           ConfigBackupEncryption  `json:"encryption"`
+	LastSuccessfulBackup ConfigBackupLastSuccess `json:"lastSuccessfulBackup"`
+	Schedule             ConfigBackupSchedule    `json:"schedule"`
+	LastSession          *SessionsData           `json:"-"`
+}
+
+type ConfigBackupEncryption struct {
+	IsEnabled  bool   `json:"isEnabled"`
+	PasswordID string `json:"passwordId"`
+}
+
+type ConfigBackupLastSuccess struct {
+	LastSuccessfulTime *time.Time `json:"lastSuccessfulTime,omitempty"`
+	SessionID          *string    `json:"sessionId,omitempty"`
+}
+
+type ConfigBackupSchedule struct {
+	IsEnabled bool `json:"isEnabled"`
+}
+
+// LastSuccessAge returns the time passed since the last successful configuration backup,
+// false is returned if the configuration was never backed up
+func (c ConfigBackup) LastSuccessAge(now time.Time) (time.Duration, bool) {
+	if c.LastSuccessfulBackup.LastSuccessfulTime == nil || c.LastSuccessfulBackup.LastSuccessfulTime.IsZero() {
+		return 0, false
+	}
+
+	return now.Sub(*c.LastSuccessfulBackup.LastSuccessfulTime), true
+}
+
+func (v *Veeam) GetConfigBackup() error {
+	v.log.Info("Collecting configuration backup information")
+
+	resp, err := v.cl.GetConfigBackupOptionsWithResponse(v.ctx, &client.GetConfigBackupOptionsParams{
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get configuration backup: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get configuration backup: %w", err)
+	}
+
+	var cb ConfigBackup
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&cb); err != nil {
+		return fmt.Errorf("could not parse configuration backup: %v", err)
+	}
+
+	sessionType := client.ConfigurationBackup
+	orderColumn := client.ESessionsFiltersOrderColumnCreationTime
+	orderAsc := false
+	limit := int32(1)
+
+	sr, err := v.cl.GetAllSessionsWithResponse(v.ctx, &client.GetAllSessionsParams{
+		TypeFilter:  &sessionType,
+		OrderColumn: &orderColumn,
+		OrderAsc:    &orderAsc,
+		Limit:       &limit,
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get configuration backup sessions: %w", err)
+	}
+
+	if err = checkResponse(sr.HTTPResponse, sr.Body); err != nil {
+		return fmt.Errorf("could not get configuration backup sessions: %w", err)
+	}
+
+	var ses Sessions
+	if err = json.NewDecoder(bytes.NewBuffer(sr.Body)).Decode(&ses); err != nil {
+		return fmt.Errorf("could not parse configuration backup sessions: %v", err)
+	}
+
+	if len(ses.Data) > 0 {
+		cb.LastSession = &ses.Data[0]
+	}
+
+	v.ConfigBackup = cb
+
+	return nil
+}
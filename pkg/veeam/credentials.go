@@ -0,0 +1,135 @@
+package veeam
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
+)
+
+// Credentials only holds the metadata of the credentials records, secrets are never decoded
+type Credentials struct {
+	Data       []CredentialsData `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+type CredentialsData struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	// CreationTime is the only timestamp of a credentials record, the api does not report when it was last changed
+	CreationTime time.Time `json:"creationTime"`
+}
+
+type Certificate struct {
+	Subject      string    `json:"subject"`
+	IssuedBy     string    `json:"issuedBy"`
+	SerialNumber string    `json:"serialNumber"`
+	Thumbprint   string    `json:"thumbprint"`
+	ValidFrom    time.Time `json:"validFrom"`
+	ValidBy      time.Time `json:"validBy"`
+}
+
+// ExpiresIn returns the time left until the certificate expires, negative once expired
+func (c Certificate) ExpiresIn(now time.Time) time.Duration {
+	return c.ValidBy.Sub(now)
+}
+
+// peerCertificate records the leaf certificate presented by the veeam server during tls handshakes
+type peerCertificate struct {
+	mu   sync.Mutex
+	cert *x509.Certificate
+}
+
+func (p *peerCertificate) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	p.cert = cs.PeerCertificates[0]
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *peerCertificate) get() *x509.Certificate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.cert
+}
+
+func (v *Veeam) GetCredentials() error {
+	v.log.Info("Collecting credentials information")
+
+	resp, err := v.cl.GetAllCredsWithResponse(v.ctx, &client.GetAllCredsParams{
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get credentials: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&creds); err != nil {
+		return fmt.Errorf("could not parse credentials: %v", err)
+	}
+
+	v.Credentials = creds
+
+	return nil
+}
+
+// GetCertificate collects the server certificate from the api,
+// the certificate seen by the tls client takes precedence as it is the one presented to api clients
+func (v *Veeam) GetCertificate() error {
+	v.log.Info("Collecting server certificate information")
+
+	var cert Certificate
+
+	resp, err := v.cl.GetServerCertificateWithResponse(v.ctx, &client.GetServerCertificateParams{
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err == nil {
+		err = checkResponse(resp.HTTPResponse, resp.Body)
+	}
+
+	if err == nil {
+		err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&cert)
+	}
+
+	if peer := v.peerCert.get(); peer != nil {
+		sum := sha1.Sum(peer.Raw)
+		v.Certificate = Certificate{
+			Subject:      peer.Subject.String(),
+			IssuedBy:     peer.Issuer.String(),
+			SerialNumber: strings.ToUpper(peer.SerialNumber.Text(16)),
+			Thumbprint:   strings.ToUpper(hex.EncodeToString(sum[:])),
+			ValidFrom:    peer.NotBefore,
+			ValidBy:      peer.NotAfter,
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not get server certificate: %w", err)
+	}
+
+	v.Certificate = cert
+
+	return nil
+}
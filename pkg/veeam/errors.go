@@ -0,0 +1,93 @@
+package veeam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of the veeam api errors, an *APIError matches its kind with errors.Is
+var (
+	// ErrAuth is a 401, the token expired or the credentials are wrong
+	ErrAuth = errors.New("veeam api authentication failed")
+	// ErrPermission is a 403, the user is not allowed to read the resource
+	ErrPermission = errors.New("veeam api permission denied")
+	// ErrNotFound is a 404, the resource does not exist or the endpoint is not supported by the server version
+	ErrNotFound = errors.New("veeam api resource not found")
+	// ErrServer is a 5xx, the server failed to handle the request
+	ErrServer = errors.New("veeam api server error")
+)
+
+// APIError is an error response of the veeam api, decoded from the veeam error model
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Path       string `json:"-"`
+	ErrorCode  string `json:"errorCode"`
+	Message    string `json:"message"`
+	ResourceID string `json:"resourceId"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s returned %d", e.Path, e.StatusCode)
+	if e.ErrorCode != "" {
+		msg += " " + e.ErrorCode
+	}
+
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	if e.ResourceID != "" {
+		msg += fmt.Sprintf(" (resource %s)", e.ResourceID)
+	}
+
+	return msg
+}
+
+// Kind returns the kind of the error, nil for the other client errors
+func (e *APIError) Kind() error {
+	return statusKind(e.StatusCode)
+}
+
+func (e *APIError) Is(target error) bool {
+	kind := e.Kind()
+	return kind != nil && kind == target
+}
+
+func statusKind(status int) error {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrAuth
+	case status == http.StatusForbidden:
+		return ErrPermission
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
+
+// checkResponse returns an *APIError if the response is not a success, bodies not following the error model
+// are kept as the message
+func checkResponse(resp *http.Response, body []byte) error {
+	if resp == nil || resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	e := &APIError{StatusCode: resp.StatusCode}
+	if resp.Request != nil {
+		e.Path = resp.Request.URL.Path
+	}
+
+	if err := json.Unmarshal(body, e); err != nil || (e.ErrorCode == "" && e.Message == "") {
+		e.Message = string(body)
+		if len(e.Message) > 200 {
+			e.Message = e.Message[:200] + "..."
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,129 @@
+package veeam
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+)
+
+func TestAPIErrors(t *testing.T) {
+	v, srv := newResilientVeeam(t, config.Retry{}, config.CircuitBreaker{})
+
+	srv.InjectError("/api/v1/credentials", http.StatusForbidden, "Forbidden", 1)
+
+	err := v.GetCredentials()
+	if !errors.Is(err, ErrPermission) || errors.Is(err, ErrServer) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden ||
+		apiErr.ErrorCode != "Forbidden" || apiErr.Path != "/api/v1/credentials" {
+		t.Errorf("unexpected api error %+v", apiErr)
+	}
+
+	// errors of endpoints not covered by the sdk client are typed as well
+	srv.InjectError("/api/v1/backupInfrastructure/wanAccelerators", http.StatusInternalServerError, "UnknownError", 1)
+
+	if err = v.GetWanAccelerators(); !errors.Is(err, ErrServer) {
+		t.Errorf("expected a server error, got %v", err)
+	}
+
+	// the cycle goes on without the data the user can not read, but fails on server errors
+	srv.InjectError("/api/v1/credentials", http.StatusForbidden, "Forbidden", -1)
+
+	if err = v.Collect(); err != nil {
+		t.Fatalf("expected the permission error to be skipped: %v", err)
+	}
+
+	srv.InjectError("/api/v1/jobs", http.StatusInternalServerError, "UnknownError", 1)
+
+	if err = v.Collect(); !errors.Is(err, ErrServer) {
+		t.Fatalf("expected the server error to fail the cycle, got %v", err)
+	}
+
+	if v.API.PermissionErrors != 3 || v.API.ServerErrors != 2 {
+		t.Errorf("unexpected stats %+v", v.API)
+	}
+}
+
+func TestCollectorSkipped(t *testing.T) {
+	v, srv := newTestVeeam(t)
+
+	if err := v.Collect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.Credentials.Data) == 0 {
+		t.Fatal("expected credentials")
+	}
+
+	// the credentials of the first cycle are not kept once the user can not read them anymore
+	srv.InjectError("/api/v1/credentials", http.StatusForbidden, "Forbidden", -1)
+
+	if err := v.Collect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.Credentials.Data) != 0 {
+		t.Errorf("expected the credentials of the skipped collector to be cleared, got %d", len(v.Credentials.Data))
+	}
+
+	states := make(map[string]CollectorState)
+	for _, c := range v.Collectors {
+		states[c.Name] = c.State
+	}
+
+	if len(states) != 16 || states["credentials"] != CollectorSkipped || states["jobs"] != CollectorOK {
+		t.Errorf("unexpected collector states %v", states)
+	}
+
+	srv.InjectError("/api/v1/backupInfrastructure/wanAccelerators", http.StatusInternalServerError, "UnknownError", 1)
+
+	if err := v.Collect(); err == nil {
+		t.Fatal("expected the server error to fail the cycle")
+	}
+
+	for _, c := range v.Collectors {
+		if c.Name == "wan_accelerators" && (c.State != CollectorFailed || !errors.Is(c.Err, ErrServer)) {
+			t.Errorf("expected the wan accelerators collector to fail, got %+v", c)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	v, srv := newTestVeeam(t)
+
+	if err := v.Collect(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireTokens()
+
+	// the expired token is replaced, instead of collecting empty data
+	if err := v.Collect(); err != nil {
+		t.Fatalf("expected to login again: %v", err)
+	}
+
+	if len(v.Jobs.Data) == 0 || v.API.AuthErrors == 0 {
+		t.Errorf("expected the jobs after logging in again, got %d jobs and %+v", len(v.Jobs.Data), v.API)
+	}
+
+	srv.ExpireTokens()
+
+	from := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if _, err := v.SessionsPage(from, from.Add(24*time.Hour), 0, 10); err != nil {
+		t.Errorf("expected the backfill to login again: %v", err)
+	}
+
+	// wrong credentials are not retried forever
+	srv.ExpireTokens()
+	srv.Password = "rotated"
+
+	if err := v.Collect(); !errors.Is(err, ErrAuth) {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package veeam
+
+import (
+	"fmt"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+)
+
+func newFilter(conf config.Config) (*filter.Filter, error) {
+	f, err := filter.New(conf.FilterRules())
+	if err != nil {
+		return nil, fmt.Errorf("could not create filter: %v", err)
+	}
+
+	return f, nil
+}
+
+// FilterObject returns the fields filter and tag rules match against
+func (s SessionsData) FilterObject() filter.Object {
+	return filter.Object{
+		filter.JobName:     s.Name,
+		filter.SessionType: s.SessionType,
+		filter.Platform:    s.PlatformName,
+	}
+}
+
+// task sessions carry neither the job name nor the object path, so only the session type is matched
+func (t TaskSessionsData) FilterObject() filter.Object {
+	return filter.Object{filter.SessionType: t.SessionType}
+}
+
+func (j JobsData) FilterObject() filter.Object {
+	return filter.Object{filter.JobName: j.Name}
+}
+
+func (j FileShareJobsData) FilterObject() filter.Object {
+	return filter.Object{filter.JobName: j.Name}
+}
+
+func (r RepositoriesData) FilterObject() filter.Object {
+	return filter.Object{filter.Repository: r.Name}
+}
+
+func (p ProxiesData) FilterObject() filter.Object {
+	return filter.Object{filter.Proxy: p.Name}
+}
+
+func (p ProxyStatesData) FilterObject() filter.Object {
+	return filter.Object{filter.Proxy: p.Name}
+}
+
+// virtual machines also get the host\name path of the inventory, which does not report folders,
+// for a rule to match a vm both in the inventory and in the backup objects
+func (b BackupObjectsData) FilterObject() filter.Object {
+	o := filter.Object{
+		filter.ObjectPath: b.Path,
+		filter.Platform:   string(b.PlatformName),
+	}
+
+	if b.Kind() == KindVirtualMachine {
+		o[filter.InventoryPath] = b.host() + `\` + b.Name
+	}
+
+	return o
+}
+
+func (o InventoryObject) FilterObject() filter.Object {
+	return filter.Object{
+		filter.ObjectPath: o.HostName + `\` + o.Name,
+		filter.Platform:   string(o.Platform),
+	}
+}
+
+func (u UnstructuredDataServersData) FilterObject() filter.Object {
+	return filter.Object{filter.ObjectPath: u.DisplayName()}
+}
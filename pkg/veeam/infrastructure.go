@@ -0,0 +1,109 @@
+package veeam
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+)
+
+type ProxyStates struct {
+	Data       []ProxyStatesData `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+type ProxyStatesData struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	HostID      string `json:"hostId"`
+	HostName    string `json:"hostName"`
+	IsDisabled  bool   `json:"isDisabled"`
+	IsOnline    bool   `json:"isOnline"`
+	IsOutOfDate bool   `json:"isOutOfDate"`
+}
+
+type WanAccelerators struct {
+	Data       []WanAcceleratorsData `json:"data"`
+	Pagination Pagination            `json:"pagination"`
+}
+
+type WanAcceleratorsData struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Server      WanAcceleratorServer `json:"server"`
+	Cache       WanAcceleratorCache  `json:"cache"`
+}
+
+type WanAcceleratorServer struct {
+	HostID                   string `json:"hostId"`
+	Description              string `json:"description"`
+	TrafficPort              int64  `json:"trafficPort"`
+	StreamsCount             int64  `json:"streamsCount"`
+	HighBandwidthModeEnabled bool   `json:"highBandwidthModeEnabled"`
+}
+
+type WanAcceleratorCache struct {
+	CacheFolder   string `json:"cacheFolder"`
+	CacheSize     int64  `json:"cacheSize"`
+	CacheSizeUnit string `json:"cacheSizeUnit"`
+}
+
+// CacheSizeBytes converts the configured cache size into bytes
+func (c WanAcceleratorCache) CacheSizeBytes() int64 {
+	switch c.CacheSizeUnit {
+	case "MB":
+		return c.CacheSize * 1024 * 1024
+	case "GB":
+		return c.CacheSize * 1024 * 1024 * 1024
+	case "TB":
+		return c.CacheSize * 1024 * 1024 * 1024 * 1024
+	default:
+		return c.CacheSize
+	}
+}
+
+// ServerByID looks up a managed server, used to resolve the hosts of proxies and wan accelerators
+func (m ManagedSevers) ServerByID(id string) (ManagedSeversData, bool) {
+	for _, s := range m.Data {
+		if s.ID == id {
+			return s, true
+		}
+	}
+
+	return ManagedSeversData{}, false
+}
+
+// IsAvailable reports whether veeam can currently reach the managed server
+func (m ManagedSeversData) IsAvailable() bool {
+	return m.Status == "Available"
+}
+
+func (v *Veeam) GetProxyStates() error {
+	v.log.Info("Collecting proxy states information")
+
+	var ps ProxyStates
+	if err := v.getJSON("/api/v1/backupInfrastructure/proxies/states", url.Values{}, &ps); err != nil {
+		return fmt.Errorf("could not get proxy states: %w", err)
+	}
+
+	ps.Data = filter.Apply(v.filter, ps.Data, ProxyStatesData.FilterObject)
+	v.ProxyStates = ps
+
+	return nil
+}
+
+func (v *Veeam) GetWanAccelerators() error {
+	v.log.Info("Collecting wan accelerators information")
+
+	var wa WanAccelerators
+	if err := v.getJSON("/api/v1/backupInfrastructure/wanAccelerators", url.Values{}, &wa); err != nil {
+		return fmt.Errorf("could not get wan accelerators: %w", err)
+	}
+
+	v.WanAccelerators = wa
+
+	return nil
+}
@@ -0,0 +1,387 @@
+package veeam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
+)
+
+type Jobs struct {
+	Data       []JobsData `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type JobsData struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`
+	Description     string             `json:"description"`
+	IsDisabled      bool               `json:"isDisabled"`
+	VirtualMachines JobVirtualMachines `json:"virtualMachines"`
+}
+
+type JobVirtualMachines struct {
+	Includes []JobObject   `json:"includes"`
+	Excludes JobExclusions `json:"excludes"`
+}
+
+type JobExclusions struct {
+	VMs []JobObject `json:"vms"`
+}
+
+type JobObject struct {
+	InventoryObject InventoryObject `json:"inventoryObject"`
+}
+
+type InventoryObjects struct {
+	Data       []InventoryObject `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+type InventoryObject struct {
+	HostName string `json:"hostName"`
+	Name     string `json:"name"`
+	ObjectID string `json:"objectId"`
+	Type     string `json:"type"`
+	// Platform is not part of the api response, it is set to the platform of the browsed server
+	Platform PlatformName `json:"-"`
+}
+
+// ProtectionReport is the result of cross-referencing the virtual infrastructure inventory
+// with the backup objects and the objects included in jobs
+type ProtectionReport struct {
+	Total       int
+	Protected   int
+	Unprotected []InventoryObject
+}
+
+// key identifies the object, object ids are only unique within their vcenter or hyper-v host
+func (o InventoryObject) key() string {
+	return o.HostName + "/" + o.ObjectID
+}
+
+// Coverage returns the percentage of protected inventory objects
+func (p ProtectionReport) Coverage() float64 {
+	if p.Total == 0 {
+		return 100
+	}
+
+	return float64(p.Protected) / float64(p.Total) * 100
+}
+
+// container inventory types which protect every vm of the browsed server when included in a job
+var hostWideInventoryTypes = map[string]struct{}{
+	string(client.EVmwareInventoryTypeVCenterServer): {},
+	"HvServer":  {},
+	"HvCluster": {},
+	"SCVMM":     {},
+}
+
+// vmware container types which can be included in or excluded from a job, and the inventory hierarchy
+// holding their virtual machines
+var containerHierarchies = map[string]client.EHierarchyType{
+	string(client.EVmwareInventoryTypeDatacenter):       client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeCluster):          client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeHost):             client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeComputeResource):  client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeResourcePool):     client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeVirtualApp):       client.EHierarchyTypeHostsAndClusters,
+	string(client.EVmwareInventoryTypeFolder):           client.EHierarchyTypeVmsAndTemplates,
+	string(client.EVmwareInventoryTypeCategory):         client.EHierarchyTypeVmsAndTags,
+	string(client.EVmwareInventoryTypeTag):              client.EHierarchyTypeVmsAndTags,
+	string(client.EVmwareInventoryTypeMultitag):         client.EHierarchyTypeVmsAndTags,
+	string(client.EVmwareInventoryTypeDatastore):        client.EHierarchyTypeDatastoresAndVms,
+	string(client.EVmwareInventoryTypeDatastoreCluster): client.EHierarchyTypeDatastoresAndVms,
+}
+
+// hyper-v managed server types which can be browsed for virtual machines
+var hypervHostTypes = map[string]struct{}{
+	"HvServer":  {},
+	"HvCluster": {},
+	"SCVMM":     {},
+}
+
+func (v *Veeam) GetJobs() error {
+	v.log.Info("Collecting jobs information")
+
+	resp, err := v.cl.GetAllJobsWithResponse(v.ctx, &client.GetAllJobsParams{
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get jobs: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get jobs: %w", err)
+	}
+
+	var jobs Jobs
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&jobs); err != nil {
+		return fmt.Errorf("could not parse jobs: %v", err)
+	}
+
+	v.allJobs = jobs.Data
+	jobs.Data = filter.Apply(v.filter, jobs.Data, JobsData.FilterObject)
+	v.Jobs = jobs
+
+	return nil
+}
+
+// GetInventory browses the virtual machines of every managed hypervisor server gathered by GetManagedServers
+func (v *Veeam) GetInventory() error {
+	v.log.Info("Collecting virtual infrastructure inventory")
+
+	servers := v.ManagedSevers.Data
+	platforms := make([]PlatformName, len(servers))
+	browsed := make([]InventoryObjects, len(servers))
+
+	// hosts are browsed concurrently, the objects are merged in the order of the servers afterwards
+	if err := v.forEach(len(servers), func(i int) error {
+		s := servers[i]
+
+		var err error
+		switch _, hv := hypervHostTypes[s.Type]; {
+		case s.Type == string(client.ViHost):
+			platforms[i] = VMware
+			browsed[i], err = v.browseVmwareHost(s.Name)
+		case hv:
+			platforms[i] = HyperV
+			browsed[i], err = v.browseHypervHost(s.Name)
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not browse inventory of %s: %w", s.Name, err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+	var inv InventoryObjects
+
+	for i, objects := range browsed {
+		for _, o := range objects.Data {
+			key := o.key()
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			o.Platform = platforms[i]
+			inv.Data = append(inv.Data, o)
+		}
+	}
+
+	inv.Data = filter.Apply(v.filter, inv.Data, InventoryObject.FilterObject)
+	inv.Pagination.Total = int64(len(inv.Data))
+	inv.Pagination.Count = int64(len(inv.Data))
+	v.Inventory = inv
+
+	return nil
+}
+
+func (v *Veeam) browseVmwareHost(name string) (InventoryObjects, error) {
+	vmType := client.EVmwareInventoryTypeVirtualMachine
+
+	resp, err := v.cl.GetVmwareHostObjectWithResponse(v.ctx, name, &client.GetVmwareHostObjectParams{
+		TypeFilter:  &vmType,
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return InventoryObjects{}, err
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return InventoryObjects{}, err
+	}
+
+	var objects InventoryObjects
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&objects); err != nil {
+		return InventoryObjects{}, fmt.Errorf("could not parse inventory: %v", err)
+	}
+
+	return objects, nil
+}
+
+// browseHypervHost uses the hyper-v inventory browser, which is not covered by the veeam sdk client
+func (v *Veeam) browseHypervHost(name string) (InventoryObjects, error) {
+	var objects InventoryObjects
+	if err := v.getJSON("/api/v1/inventory/hyperv/hosts/"+url.PathEscape(name), url.Values{
+		"typeFilter": []string{"VirtualMachine"},
+	}, &objects); err != nil {
+		return InventoryObjects{}, err
+	}
+
+	return objects, nil
+}
+
+// GetJobContainers resolves the vmware containers of the jobs gathered by GetJobs into their virtual machines,
+// keyed by the container
+func (v *Veeam) GetJobContainers() error {
+	v.log.Info("Collecting virtual machines of job containers")
+
+	seen := make(map[string]struct{})
+	var containers []InventoryObject
+
+	for _, j := range v.allJobs {
+		if j.IsDisabled {
+			continue
+		}
+
+		for _, jo := range append(j.VirtualMachines.Includes, j.VirtualMachines.Excludes.VMs...) {
+			o := jo.InventoryObject
+			if _, ok := containerHierarchies[o.Type]; !ok || o.ObjectID == "" {
+				continue
+			}
+
+			if _, ok := seen[o.key()]; ok {
+				continue
+			}
+
+			seen[o.key()] = struct{}{}
+			containers = append(containers, o)
+		}
+	}
+
+	vms := make([][]InventoryObject, len(containers))
+	if err := v.forEach(len(containers), func(i int) error {
+		var err error
+		if vms[i], err = v.browseVmwareContainer(containers[i]); err != nil {
+			return fmt.Errorf("could not browse container %s of %s: %w", containers[i].Name, containers[i].HostName, err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	resolved := make(map[string][]InventoryObject, len(containers))
+	for i, c := range containers {
+		resolved[c.key()] = vms[i]
+	}
+
+	v.JobContainers = resolved
+
+	return nil
+}
+
+// browseVmwareContainer walks the inventory hierarchy below the container, returning the virtual machines
+// of the container and of the containers nested in it
+func (v *Veeam) browseVmwareContainer(c InventoryObject) ([]InventoryObject, error) {
+	hierarchy := containerHierarchies[c.Type]
+	visited := map[string]struct{}{c.Name: {}}
+	queue := []string{c.Name}
+
+	var vms []InventoryObject
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		resp, err := v.cl.GetVmwareHostObjectWithResponse(v.ctx, c.HostName, &client.GetVmwareHostObjectParams{
+			HierarchyTypeFilter:       &hierarchy,
+			ParentContainerNameFilter: &parent,
+			XApiVersion:               v.conf.Veeam.XApiVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+			return nil, err
+		}
+
+		var children InventoryObjects
+		if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&children); err != nil {
+			return nil, fmt.Errorf("could not parse inventory: %v", err)
+		}
+
+		for _, o := range children.Data {
+			if o.Type == string(client.EVmwareInventoryTypeVirtualMachine) {
+				vms = append(vms, o)
+				continue
+			}
+
+			if _, ok := visited[o.Name]; !ok {
+				visited[o.Name] = struct{}{}
+				queue = append(queue, o.Name)
+			}
+		}
+	}
+
+	return vms, nil
+}
+
+// Protection cross-references the collected inventory with backup objects and the objects of enabled jobs,
+// matching them by host and object id. Containers and excludes of jobs are resolved with GetJobContainers.
+// Jobs and backup objects are not filtered, only the inventory objects reported are.
+func (v *Veeam) Protection() ProtectionReport {
+	protected := make(map[string]struct{})
+
+	for _, b := range v.allBackupObjects {
+		if b.ObjectID != "" {
+			protected[InventoryObject{HostName: b.host(), ObjectID: b.ObjectID}.key()] = struct{}{}
+		}
+	}
+
+	for _, j := range v.allJobs {
+		if j.IsDisabled {
+			continue
+		}
+
+		excluded := v.jobVMs(j.VirtualMachines.Excludes.VMs)
+		for key := range v.jobVMs(j.VirtualMachines.Includes) {
+			if _, ok := excluded[key]; !ok {
+				protected[key] = struct{}{}
+			}
+		}
+	}
+
+	report := ProtectionReport{Total: len(v.Inventory.Data)}
+
+	for _, o := range v.Inventory.Data {
+		if _, ok := protected[o.key()]; ok {
+			report.Protected++
+			continue
+		}
+
+		report.Unprotected = append(report.Unprotected, o)
+	}
+
+	return report
+}
+
+// jobVMs returns the keys of the virtual machines covered by the objects of a job
+func (v *Veeam) jobVMs(objects []JobObject) map[string]struct{} {
+	keys := make(map[string]struct{})
+
+	for _, jo := range objects {
+		o := jo.InventoryObject
+
+		_, hostWide := hostWideInventoryTypes[o.Type]
+		_, container := containerHierarchies[o.Type]
+
+		switch {
+		// vcenter servers and standalone esxi hosts have no object id
+		case hostWide || o.ObjectID == "":
+			for _, io := range v.Inventory.Data {
+				if io.HostName == o.HostName {
+					keys[io.key()] = struct{}{}
+				}
+			}
+		case container:
+			for _, vm := range v.JobContainers[o.key()] {
+				keys[vm.key()] = struct{}{}
+			}
+		default:
+			keys[o.key()] = struct{}{}
+		}
+	}
+
+	return keys
+}
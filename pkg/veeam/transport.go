@@ -0,0 +1,340 @@
+package veeam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit breaker is open
+var ErrCircuitOpen = errors.New("veeam api circuit breaker is open")
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// APIStats are the counters of the api client since it was created
+type APIStats struct {
+	// Requests sent to the server, retries included
+	Requests int64
+	Retries  int64
+	// RateLimited counts the 429 responses
+	RateLimited int64
+	// Failures counts the requests failed after the last retry, requests refused by the open breaker included
+	Failures     int64
+	BreakerState BreakerState
+	// BreakerTrips counts how many times the breaker opened
+	BreakerTrips int64
+	// error responses by kind, after the last retry
+	AuthErrors       int64
+	PermissionErrors int64
+	NotFoundErrors   int64
+	ServerErrors     int64
+}
+
+// resilientTransport retries idempotent requests failing with a transient error, and stops sending requests
+// to a server failing every request until the breaker cooldown passed
+type resilientTransport struct {
+	next    http.RoundTripper
+	log     *slog.Logger
+	retry   config.Retry
+	breaker config.CircuitBreaker
+	timeout time.Duration
+	// sem holds a slot for every request in flight
+	sem chan struct{}
+
+	mu    sync.Mutex
+	stats APIStats
+	// failures in a row, the breaker opens at the threshold
+	failures int
+	openedAt time.Time
+}
+
+func newResilientTransport(next http.RoundTripper, conf config.Veeam, log *slog.Logger) *resilientTransport {
+	return &resilientTransport{
+		next:    next,
+		log:     log,
+		retry:   conf.Retry,
+		breaker: conf.CircuitBreaker,
+		timeout: time.Duration(conf.RequestTimeoutSeconds) * time.Second,
+		sem:     make(chan struct{}, max(conf.Concurrency, 1)),
+	}
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+
+	retries := 0
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		retries = t.retry.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.send(req)
+
+		// the caller gave up, which says nothing about the server
+		if req.Context().Err() != nil {
+			t.abandon()
+			return resp, err
+		}
+
+		delay, retry := t.retryDelay(resp, err, attempt)
+		if !retry || attempt >= retries {
+			if resp != nil {
+				t.countError(resp.StatusCode)
+			}
+
+			t.done(err != nil || resp.StatusCode >= http.StatusInternalServerError)
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			// the connection is reused once the body is read
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.log.Warn("Retrying veeam api request", "method", req.Method, "path", req.URL.Path,
+			"attempt", attempt+1, "reason", reason, "delay", delay.String())
+
+		t.mu.Lock()
+		t.stats.Retries++
+		t.mu.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			t.abandon()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// send sends a single attempt once a slot is free. The slot is held, and the attempt is bounded by
+// the request timeout, until its body is closed.
+func (t *resilientTransport) send(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	t.mu.Lock()
+	t.stats.Requests++
+	t.mu.Unlock()
+
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), t.timeout)
+	}
+
+	release := func() {
+		cancel()
+		<-t.sem
+	}
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// retryDelay returns how long to wait before retrying, and whether the attempt failed with a transient error.
+// Rate limited responses are retried after their Retry-After, unless it is longer than the max backoff.
+func (t *resilientTransport) retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	maxBackoff := time.Duration(t.retry.MaxBackoffSeconds) * time.Second
+
+	backoff := time.Duration(t.retry.BackoffMilliseconds) * time.Millisecond << attempt
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+
+	// full jitter between half and the whole backoff, so concurrent requests do not retry at once
+	if half := int64(backoff / 2); half > 0 {
+		backoff = time.Duration(half + rand.Int64N(half+1))
+	}
+
+	if err != nil {
+		return backoff, true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		t.mu.Lock()
+		t.stats.RateLimited++
+		t.mu.Unlock()
+
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return after, after <= maxBackoff
+		}
+
+		return backoff, true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return after, after <= maxBackoff
+		}
+
+		return backoff, true
+	default:
+		return 0, false
+	}
+}
+
+// retryAfter parses the Retry-After header, which is either a number of seconds or a date
+func retryAfter(h string) (time.Duration, bool) {
+	if h == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(h); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
+
+// allow refuses the request while the breaker is open. Once the cooldown passed a single request is let through,
+// its result closes or opens the breaker again.
+func (t *resilientTransport) allow() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.stats.BreakerState {
+	case BreakerOpen:
+		cooldown := time.Duration(t.breaker.CooldownSeconds) * time.Second
+		if time.Since(t.openedAt) < cooldown {
+			t.stats.Failures++
+			return fmt.Errorf("%w, retrying in %s", ErrCircuitOpen, (cooldown - time.Since(t.openedAt)).Round(time.Second))
+		}
+
+		t.stats.BreakerState = BreakerHalfOpen
+		t.log.Info("Veeam api circuit breaker half-open, sending a trial request")
+	case BreakerHalfOpen:
+		// the trial request is still running
+		t.stats.Failures++
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// countError counts the error responses by kind
+func (t *resilientTransport) countError(status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch statusKind(status) {
+	case ErrAuth:
+		t.stats.AuthErrors++
+	case ErrPermission:
+		t.stats.PermissionErrors++
+	case ErrNotFound:
+		t.stats.NotFoundErrors++
+	case ErrServer:
+		t.stats.ServerErrors++
+	}
+}
+
+// done records the result of a request for the breaker
+func (t *resilientTransport) done(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !failed {
+		if t.stats.BreakerState == BreakerHalfOpen {
+			t.log.Info("Veeam api circuit breaker closed")
+		}
+
+		t.failures = 0
+		t.stats.BreakerState = BreakerClosed
+
+		return
+	}
+
+	t.stats.Failures++
+	t.failures++
+
+	if t.breaker.FailureThreshold <= 0 {
+		return
+	}
+
+	if t.stats.BreakerState == BreakerHalfOpen || t.failures >= t.breaker.FailureThreshold {
+		if t.stats.BreakerState != BreakerOpen {
+			t.stats.BreakerTrips++
+		}
+
+		t.stats.BreakerState = BreakerOpen
+		t.openedAt = time.Now()
+		t.log.Warn("Veeam api circuit breaker opened", "failures", t.failures, "cooldown_seconds", t.breaker.CooldownSeconds)
+	}
+}
+
+// abandon records a request the caller gave up on, the next request is let through as the trial request instead
+func (t *resilientTransport) abandon() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats.BreakerState == BreakerHalfOpen {
+		t.stats.BreakerState = BreakerOpen
+	}
+}
+
+func (t *resilientTransport) Stats() APIStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats
+}
+
+// releaseBody releases the slot and the context of the attempt once the body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
@@ -0,0 +1,140 @@
+package veeam
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+)
+
+func newResilientVeeam(t *testing.T, retry config.Retry, cb config.CircuitBreaker) (*Veeam, *veeamtest.Server) {
+	t.Helper()
+
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.Veeam.Retry = retry
+	conf.Veeam.CircuitBreaker = cb
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	return v, srv
+}
+
+func TestRetry(t *testing.T) {
+	v, srv := newResilientVeeam(t, config.Retry{MaxRetries: 3, BackoffMilliseconds: 10, MaxBackoffSeconds: 1}, config.CircuitBreaker{})
+
+	srv.InjectError("/api/v1/jobs", http.StatusServiceUnavailable, "ServiceUnavailable", 2)
+
+	if err := v.GetJobs(); err != nil {
+		t.Fatalf("expected the jobs to be collected after retrying: %v", err)
+	}
+
+	if got := srv.Requests("/api/v1/jobs"); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+
+	// client errors are not retried
+	srv.InjectError("/api/v1/jobs", http.StatusNotFound, "NotFound", 1)
+	_ = v.GetJobs()
+
+	if got := srv.Requests("/api/v1/jobs"); got != 4 {
+		t.Errorf("expected the not found response not to be retried, got %d requests", got)
+	}
+
+	if stats := v.rt.Stats(); stats.Retries != 2 || stats.Failures != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// longer than the max backoff, so it is not waited for
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	rt := newResilientTransport(http.DefaultTransport, config.Veeam{
+		Retry: config.Retry{MaxRetries: 3, BackoffMilliseconds: 10, MaxBackoffSeconds: 5},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hc := &http.Client{Transport: rt}
+
+	start := time.Now()
+
+	resp, err := hc.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 2 {
+		t.Fatalf("expected the second rate limited response after 2 requests, got %s after %d", resp.Status, requests.Load())
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for the Retry-After, waited %s", elapsed)
+	}
+
+	if stats := rt.Stats(); stats.RateLimited != 2 || stats.Retries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	v, srv := newResilientVeeam(t, config.Retry{}, config.CircuitBreaker{FailureThreshold: 2, CooldownSeconds: 1})
+
+	srv.InjectError("/api/v1/jobs", http.StatusServiceUnavailable, "ServiceUnavailable", -1)
+
+	for range 2 {
+		_ = v.GetJobs()
+	}
+
+	// the server is not requested while the breaker is open
+	err := v.GetJobs()
+	if err == nil || !strings.Contains(err.Error(), ErrCircuitOpen.Error()) {
+		t.Fatalf("expected the breaker to be open, got %v", err)
+	}
+
+	if got := srv.Requests("/api/v1/jobs"); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+
+	if stats := v.rt.Stats(); stats.BreakerState != BreakerOpen || stats.BreakerTrips != 1 || stats.Failures != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the trial request after the cooldown closes the breaker
+	srv.ClearErrors()
+	time.Sleep(1100 * time.Millisecond)
+
+	if err = v.GetJobs(); err != nil {
+		t.Fatalf("expected the trial request to succeed: %v", err)
+	}
+
+	if stats := v.rt.Stats(); stats.BreakerState != BreakerClosed {
+		t.Errorf("expected the breaker to be closed, got %s", stats.BreakerState)
+	}
+}
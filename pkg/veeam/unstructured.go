@@ -0,0 +1,182 @@
+package veeam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/google/uuid"
+	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
+)
+
+const fileBackupJobType = client.EJobType("FileBackup")
+
+type UnstructuredDataServers struct {
+	Data       []UnstructuredDataServersData `json:"data"`
+	Pagination Pagination                    `json:"pagination"`
+}
+
+type UnstructuredDataServersData struct {
+	ID                  string                 `json:"id"`
+	Type                UnstructuredServerType `json:"type"`
+	HostID              *string                `json:"hostId,omitempty"`
+	Path                *string                `json:"path,omitempty"`
+	FriendlyName        *string                `json:"friendlyName,omitempty"`
+	AccessCredentialsID *string                `json:"accessCredentialsId,omitempty"`
+	Processing          Processing             `json:"processing"`
+}
+
+type Processing struct {
+	BackupProxies        BackupProxies `json:"backupProxies"`
+	CacheRepositoryID    string        `json:"cacheRepositoryId"`
+	BackupIOControlLevel string        `json:"backupIOControlLevel"`
+}
+
+type BackupProxies struct {
+	AutoSelectEnabled bool     `json:"autoSelectEnabled"`
+	ProxyIDs          []string `json:"proxyIds"`
+}
+
+type UnstructuredServerType string
+
+const (
+	UnstructuredFileServer   UnstructuredServerType = "FileServer"
+	UnstructuredSMBShare     UnstructuredServerType = "SMBShare"
+	UnstructuredNFSShare     UnstructuredServerType = "NFSShare"
+	UnstructuredNASFiler     UnstructuredServerType = "NASFiler"
+	UnstructuredAmazonS3     UnstructuredServerType = "AmazonS3"
+	UnstructuredS3Compatible UnstructuredServerType = "S3Compatible"
+	UnstructuredAzureBlob    UnstructuredServerType = "AzureBlob"
+)
+
+// IsObjectStorage reports whether the server is an object storage rather than a file share
+func (t UnstructuredServerType) IsObjectStorage() bool {
+	return t == UnstructuredAmazonS3 || t == UnstructuredS3Compatible || t == UnstructuredAzureBlob
+}
+
+// DisplayName returns the best human-readable identifier the api provides for the server
+func (u UnstructuredDataServersData) DisplayName() string {
+	switch {
+	case u.FriendlyName != nil && *u.FriendlyName != "":
+		return *u.FriendlyName
+	case u.Path != nil && *u.Path != "":
+		return *u.Path
+	case u.HostID != nil && *u.HostID != "":
+		return *u.HostID
+	default:
+		return u.ID
+	}
+}
+
+type FileShareJobs struct {
+	Data       []FileShareJobsData `json:"data"`
+	Pagination Pagination          `json:"pagination"`
+}
+
+type FileShareJobsData struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	Type             string                 `json:"type"`
+	Description      string                 `json:"description"`
+	IsDisabled       bool                   `json:"isDisabled"`
+	Objects          []FileShareJobObject   `json:"objects"`
+	BackupRepository FileShareJobRepository `json:"backupRepository"`
+}
+
+type FileShareJobObject struct {
+	FileServerID string `json:"fileServerId"`
+	Path         string `json:"path"`
+}
+
+type FileShareJobRepository struct {
+	BackupRepositoryID string `json:"backupRepositoryId"`
+}
+
+func (v *Veeam) GetUnstructuredDataServers() error {
+	v.log.Info("Collecting unstructured data servers information")
+
+	var uds UnstructuredDataServers
+	if err := v.getJSON("/api/v1/inventory/unstructuredDataServers", url.Values{}, &uds); err != nil {
+		return fmt.Errorf("could not get unstructured data servers: %w", err)
+	}
+
+	uds.Data = filter.Apply(v.filter, uds.Data, UnstructuredDataServersData.FilterObject)
+	v.UnstructuredDataServers = uds
+
+	return nil
+}
+
+func (v *Veeam) GetFileShareJobs() error {
+	v.log.Info("Collecting file share jobs information")
+
+	jobType := fileBackupJobType
+	resp, err := v.cl.GetAllJobsWithResponse(v.ctx, &client.GetAllJobsParams{
+		TypeFilter:  &jobType,
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get file share jobs: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get file share jobs: %w", err)
+	}
+
+	var jobs FileShareJobs
+	if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&jobs); err != nil {
+		return fmt.Errorf("could not parse file share jobs: %v", err)
+	}
+
+	jobs.Data = filter.Apply(v.filter, jobs.Data, FileShareJobsData.FilterObject)
+	v.FileShareJobs = jobs
+
+	return nil
+}
+
+// GetFileShareSessions collects the sessions of the jobs gathered by GetFileShareJobs
+func (v *Veeam) GetFileShareSessions() error {
+	v.log.Info("Collecting file share sessions information")
+
+	jobs := v.FileShareJobs.Data
+	perJob := make([]Sessions, len(jobs))
+
+	if err := v.forEach(len(jobs), func(i int) error {
+		uid, err := uuid.Parse(jobs[i].ID)
+		if err != nil {
+			return fmt.Errorf("could not parse file share job uuid: %v", err)
+		}
+
+		resp, err := v.cl.GetAllSessionsWithResponse(v.ctx, &client.GetAllSessionsParams{
+			JobIdFilter: &uid,
+			XApiVersion: v.conf.Veeam.XApiVersion,
+		})
+		if err != nil {
+			return fmt.Errorf("could not get file share sessions: %w", err)
+		}
+
+		if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+			return fmt.Errorf("could not get file share sessions: %w", err)
+		}
+
+		if err = json.NewDecoder(bytes.NewBuffer(resp.Body)).Decode(&perJob[i]); err != nil {
+			return fmt.Errorf("could not parse file share sessions: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var all Sessions
+	for _, ses := range perJob {
+		all.Data = append(all.Data, filter.Apply(v.filter, ses.Data, SessionsData.FilterObject)...)
+	}
+
+	all.Pagination.Total = int64(len(all.Data))
+	all.Pagination.Count = int64(len(all.Data))
+	v.FileShareSessions = all
+
+	return nil
+}
@@ -7,12 +7,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ZeljkoBenovic/govein/pkg/config"
-	"github.com/deepmap/oapi-codegen/v2/pkg/securityprovider"
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
 	"github.com/google/uuid"
 	"github.com/veeamhub/veeam-vbr-sdk-go/v2/pkg/client"
 )
@@ -22,6 +27,23 @@ type Veeam struct {
 	conf config.Config
 	log  *slog.Logger
 	cl   *client.ClientWithResponses
+	hc   *http.Client
+	auth client.RequestEditorFn
+
+	// tokenCl is the unauthorized client used to login
+	tokenCl *client.ClientWithResponses
+	token   *bearerToken
+	// the credentials of the last login, used to login again once the token expired
+	username string
+	password string
+
+	peerCert *peerCertificate
+	filter   *filter.Filter
+	rt       *resilientTransport
+
+	// the jobs and backup objects before filtering, a vm is protected by a job even if the job is filtered out
+	allJobs          []JobsData
+	allBackupObjects []BackupObjectsData
 
 	ServerInfo      ServerInfo
 	Sessions        Sessions
@@ -30,6 +52,22 @@ type Veeam struct {
 	AllRepositories AllRepositories
 	Proxies         Proxies
 	BackupObjects   BackupObjects
+
+	UnstructuredDataServers UnstructuredDataServers
+	FileShareJobs           FileShareJobs
+	FileShareSessions       Sessions
+	ProxyStates             ProxyStates
+	WanAccelerators         WanAccelerators
+	ConfigBackup            ConfigBackup
+	Jobs                    Jobs
+	Inventory               InventoryObjects
+	JobContainers           map[string][]InventoryObject
+	Credentials             Credentials
+	Certificate             Certificate
+	// Collectors are the outcomes of the collectors run by the last collection
+	Collectors []CollectorStatus
+	// API are the counters of the api client at the end of the last collection
+	API APIStats
 }
 
 type ServerInfo struct {
@@ -45,28 +83,30 @@ type ServerInfo struct {
 }
 
 type Sessions struct {
-	Data []struct {
-		SessionType     string    `json:"sessionType"`
-		State           string    `json:"state"`
-		PlatformName    string    `json:"platformName"`
-		ID              string    `json:"id"`
-		Name            string    `json:"name"`
-		JobID           string    `json:"jobId"`
-		CreationTime    time.Time `json:"creationTime"`
-		EndTime         time.Time `json:"endTime"`
-		ProgressPercent int       `json:"progressPercent"`
-		Result          struct {
-			Result     string `json:"result"`
-			Message    string `json:"message"`
-			IsCanceled bool   `json:"isCanceled"`
-		} `json:"result"`
-		ResourceID        string      `json:"resourceId"`
-		ResourceReference string      `json:"resourceReference"`
-		ParentSessionID   interface{} `json:"parentSessionId"`
-		Usn               int         `json:"usn"`
-		PlatformID        string      `json:"platformId"`
-	} `json:"data"`
-	Pagination Pagination `json:"pagination"`
+	Data       []SessionsData `json:"data"`
+	Pagination Pagination     `json:"pagination"`
+}
+
+type SessionsData struct {
+	SessionType     string    `json:"sessionType"`
+	State           string    `json:"state"`
+	PlatformName    string    `json:"platformName"`
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	JobID           string    `json:"jobId"`
+	CreationTime    time.Time `json:"creationTime"`
+	EndTime         time.Time `json:"endTime"`
+	ProgressPercent int       `json:"progressPercent"`
+	Result          struct {
+		Result     string `json:"result"`
+		Message    string `json:"message"`
+		IsCanceled bool   `json:"isCanceled"`
+	} `json:"result"`
+	ResourceID        string      `json:"resourceId"`
+	ResourceReference string      `json:"resourceReference"`
+	ParentSessionID   interface{} `json:"parentSessionId"`
+	Usn               int         `json:"usn"`
+	PlatformID        string      `json:"platformId"`
 }
 
 type ManagedSevers struct {
@@ -124,6 +164,17 @@ type SingleRepositoryData struct {
 	IsOnline    bool    `json:"isOnline"`
 }
 
+// RepositoryByID looks up a repository by its id
+func (r AllRepositories) RepositoryByID(id string) (RepositoriesData, bool) {
+	for _, d := range r.Data {
+		if d.ID == id {
+			return d, true
+		}
+	}
+
+	return RepositoriesData{}, false
+}
+
 type AllRepositories struct {
 	Data       []RepositoriesData `json:"data"`
 	Pagination Pagination         `json:"pagination"`
@@ -214,7 +265,7 @@ type BackupObjects struct {
 }
 
 type BackupObjectsData struct {
-	ViType             ViType       `json:"viType"`
+	ViType             ViType       `json:"viType,omitempty"`
 	ObjectID           string       `json:"objectId"`
 	Path               string       `json:"path"`
 	PlatformName       PlatformName `json:"platformName"`
@@ -228,13 +279,25 @@ type BackupObjectsData struct {
 type PlatformName string
 
 const (
-	VMware PlatformName = "VMware"
+	VMware          PlatformName = "VMware"
+	HyperV          PlatformName = "HyperV"
+	Vcd             PlatformName = "Vcd"
+	WindowsPhysical PlatformName = "WindowsPhysical"
+	LinuxPhysical   PlatformName = "LinuxPhysical"
+	NasBackup       PlatformName = "NasBackup"
+	Tape            PlatformName = "Tape"
+	CustomPlatform  PlatformName = "CustomPlatform"
 )
 
 type Type string
 
 const (
-	VM Type = "VM"
+	VM            Type = "VM"
+	Computer      Type = "Computer"
+	FileShare     Type = "FileShare"
+	FileServer    Type = "FileServer"
+	NASFiler      Type = "NASFiler"
+	ObjectStorage Type = "ObjectStorage"
 )
 
 type ViType string
@@ -243,58 +306,261 @@ const (
 	VirtualMachine ViType = "VirtualMachine"
 )
 
-func NewVeeam(ctx context.Context, conf config.Config, log *slog.Logger) (*Veeam, error) {
-	tlsClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: conf.Veeam.TrustSelfSignedCert,
-			},
-		},
+// ObjectKind groups backup objects by the kind of workload they protect
+type ObjectKind string
+
+const (
+	KindVirtualMachine ObjectKind = "VirtualMachine"
+	KindComputer       ObjectKind = "Computer"
+	KindFileShare      ObjectKind = "FileShare"
+	KindObjectStorage  ObjectKind = "ObjectStorage"
+	KindUnknown        ObjectKind = "Unknown"
+)
+
+// host returns the vcenter or hyper-v host of a virtual machine, the path of a backup object starts with it
+func (b BackupObjectsData) host() string {
+	host, _, _ := strings.Cut(b.Path, `\`)
+	return host
+}
+
+// Kind returns the workload kind of the backup object.
+// Only VMware objects carry a viType, so the object type and platform are used for everything else.
+func (b BackupObjectsData) Kind() ObjectKind {
+	switch {
+	case b.Type == VM || b.ViType == VirtualMachine:
+		return KindVirtualMachine
+	case b.Type == FileShare || b.Type == FileServer || b.Type == NASFiler:
+		return KindFileShare
+	case b.Type == ObjectStorage:
+		return KindObjectStorage
+	case b.Type == Computer || b.PlatformName == WindowsPhysical || b.PlatformName == LinuxPhysical:
+		return KindComputer
+	case b.PlatformName == NasBackup:
+		return KindFileShare
+	default:
+		return KindUnknown
 	}
+}
 
-	cl, err := client.NewClientWithResponses(conf.Veeam.Host, client.WithHTTPClient(tlsClient))
-	if err != nil {
-		return nil, err
+// Option customizes the veeam client
+type Option func(*options)
+
+type options struct {
+	recorder *Recorder
+}
+
+// WithRecorder records every api response into the recorder
+func WithRecorder(rec *Recorder) Option {
+	return func(o *options) {
+		o.recorder = rec
 	}
+}
 
-	rl, err := cl.CreateTokenWithFormdataBodyWithResponse(context.Background(), &client.CreateTokenParams{
-		XApiVersion: conf.Veeam.XApiVersion,
-	}, client.CreateTokenFormdataRequestBody{
-		GrantType: "password",
-		Username:  &conf.Veeam.Username,
-		Password:  &conf.Veeam.Password,
-	})
+func NewVeeam(ctx context.Context, conf config.Config, log *slog.Logger, opts ...Option) (*Veeam, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	peerCert := &peerCertificate{}
+
+	f, err := newFilter(conf)
 	if err != nil {
 		return nil, err
 	}
 
-	if rl.JSON200 == nil {
-		log.Error("Error creating Veeam Token", "auth_response", string(rl.Body))
-		return nil, errors.New("error creating Veeam Token")
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: conf.Veeam.TrustSelfSignedCert,
+			VerifyConnection:   peerCert.verifyConnection,
+		},
+	}
+
+	if conf.Veeam.ReplayFile != "" {
+		b, err := LoadBundle(conf.Veeam.ReplayFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load replay bundle: %v", err)
+		}
+
+		log.Info("Replaying veeam responses from bundle", "file", conf.Veeam.ReplayFile, "captured_at", b.CapturedAt)
+		transport = NewReplayTransport(b)
 	}
 
-	bearerTokenProvider, bearerTokenProviderErr := securityprovider.NewSecurityProviderBearerToken(rl.JSON200.AccessToken)
-	if bearerTokenProviderErr != nil {
-		panic(bearerTokenProviderErr)
+	if o.recorder != nil {
+		o.recorder.next = transport
+		transport = o.recorder
 	}
 
+	rt := newResilientTransport(transport, conf.Veeam, log.WithGroup("veeam"))
+	tlsClient := &http.Client{Transport: rt}
+
+	cl, err := client.NewClientWithResponses(conf.Veeam.Host, client.WithHTTPClient(tlsClient))
+	if err != nil {
+		return nil, err
+	}
+
+	token := &bearerToken{}
+
 	authcl, err := client.NewClientWithResponses(
 		conf.Veeam.Host,
-		client.WithRequestEditorFn(bearerTokenProvider.Intercept),
+		client.WithRequestEditorFn(token.Intercept),
 		client.WithHTTPClient(tlsClient),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Veeam{
+	v := &Veeam{
 		ctx:          ctx,
 		conf:         conf,
 		cl:           authcl,
+		tokenCl:      cl,
+		hc:           tlsClient,
+		auth:         token.Intercept,
+		token:        token,
+		peerCert:     peerCert,
+		filter:       f,
+		rt:           rt,
 		log:          log.WithGroup("veeam"),
 		ServerInfo:   ServerInfo{},
 		Repositories: make([]SingleRepository, 0),
-	}, nil
+	}
+
+	if err = v.login(conf.Veeam.Username, conf.Veeam.Password); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// bearerToken authorizes the api requests, the token is replaced when logging in again
+type bearerToken struct {
+	mu    sync.RWMutex
+	token string
+}
+
+func (b *bearerToken) Intercept(_ context.Context, req *http.Request) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	req.Header.Set("Authorization", "Bearer "+b.token)
+
+	return nil
+}
+
+func (b *bearerToken) set(token string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.token = token
+}
+
+func (v *Veeam) login(username, password string) error {
+	rl, err := v.tokenCl.CreateTokenWithFormdataBodyWithResponse(context.Background(), &client.CreateTokenParams{
+		XApiVersion: v.conf.Veeam.XApiVersion,
+	}, client.CreateTokenFormdataRequestBody{
+		GrantType: "password",
+		Username:  &username,
+		Password:  &password,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = checkResponse(rl.HTTPResponse, rl.Body); err != nil {
+		return fmt.Errorf("error creating Veeam Token: %w", err)
+	}
+
+	if rl.JSON200 == nil {
+		v.log.Error("Error creating Veeam Token", "auth_response", string(rl.Body))
+		return errors.New("error creating Veeam Token")
+	}
+
+	v.token.set(rl.JSON200.AccessToken)
+	v.username, v.password = username, password
+
+	return nil
+}
+
+// authorized runs fn, logging in again and running it once more if the token was refused,
+// as the token expires long before a collection interval
+func (v *Veeam) authorized(fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrAuth) {
+		return err
+	}
+
+	v.log.Info("Veeam api token was refused, logging in again", "error", err)
+
+	if err = v.login(v.username, v.password); err != nil {
+		return fmt.Errorf("could not login again: %w", err)
+	}
+
+	return fn()
+}
+
+// SetConfig swaps the settings used by the collectors, such as the filters.
+// Connection settings, such as the host or credentials, need a new client instead.
+func (v *Veeam) SetConfig(conf config.Config) error {
+	f, err := newFilter(conf)
+	if err != nil {
+		return err
+	}
+
+	v.conf = conf
+	v.filter = f
+
+	return nil
+}
+
+// SetCredentials logs in again with the new credentials, such as a password rotated in vault.
+// The current token is kept if the login fails.
+func (v *Veeam) SetCredentials(username, password string) error {
+	if err := v.login(username, password); err != nil {
+		return fmt.Errorf("could not login with new credentials: %v", err)
+	}
+
+	v.log.Info("Logged in with new credentials", "username", username)
+
+	return nil
+}
+
+// getJSON requests an api endpoint which is not covered by the veeam sdk client and decodes the response into out
+func (v *Veeam) getJSON(path string, query url.Values, out any) error {
+	u, err := url.JoinPath(v.conf.Veeam.Host, path)
+	if err != nil {
+		return fmt.Errorf("could not build request url: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(v.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %v", err)
+	}
+
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-api-version", v.conf.Veeam.XApiVersion)
+
+	if err = v.auth(v.ctx, req); err != nil {
+		return fmt.Errorf("could not authorize request: %v", err)
+	}
+
+	resp, err := v.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if err = checkResponse(resp, body); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 func (v *Veeam) Ping() error {
@@ -305,8 +571,8 @@ func (v *Veeam) Ping() error {
 		return err
 	}
 
-	if rsi.StatusCode() != http.StatusOK {
-		return fmt.Errorf("veeam server status check failed: %s", rsi.Status())
+	if err = checkResponse(rsi.HTTPResponse, rsi.Body); err != nil {
+		return fmt.Errorf("veeam server status check failed: %w", err)
 	}
 
 	v.log.Info("Veeam server status check", "status", rsi.Status())
@@ -320,6 +586,142 @@ func (v *Veeam) Ping() error {
 	return nil
 }
 
+// Collect runs every collector, collectors depending on data gathered by others run after them.
+// The collectors of a stage run concurrently, each of them sets its own part of the snapshot.
+func (v *Veeam) Collect() error {
+	stages := [][]collector{
+		{
+			{CollectorSessions, v.GetSessions, func() { v.Sessions = Sessions{} }},
+			{CollectorManagedServers, v.GetManagedServers, func() { v.ManagedSevers = ManagedSevers{} }},
+			{CollectorRepositories, v.GetRepositories, func() { v.AllRepositories, v.Repositories = AllRepositories{}, nil }},
+			{CollectorProxies, v.GetProxies, func() { v.Proxies = Proxies{} }},
+			{CollectorProxyStates, v.GetProxyStates, func() { v.ProxyStates = ProxyStates{} }},
+			{CollectorWanAccelerators, v.GetWanAccelerators, func() { v.WanAccelerators = WanAccelerators{} }},
+			{CollectorJobs, v.GetJobs, func() { v.Jobs, v.allJobs = Jobs{}, nil }},
+			{CollectorConfigBackup, v.GetConfigBackup, func() { v.ConfigBackup = ConfigBackup{} }},
+			{CollectorCredentials, v.GetCredentials, func() { v.Credentials = Credentials{} }},
+			{CollectorCertificate, v.GetCertificate, func() { v.Certificate = Certificate{} }},
+			{CollectorBackupObjects, v.GetBackupObjects, func() { v.BackupObjects, v.allBackupObjects = BackupObjects{}, nil }},
+			{CollectorUnstructuredDataServers, v.GetUnstructuredDataServers, func() { v.UnstructuredDataServers = UnstructuredDataServers{} }},
+			{CollectorFileShareJobs, v.GetFileShareJobs, func() { v.FileShareJobs = FileShareJobs{} }},
+		},
+		// these use the managed servers, jobs and file share jobs of the first stage
+		{
+			{CollectorInventory, v.GetInventory, func() { v.Inventory = InventoryObjects{} }},
+			{CollectorJobContainers, v.GetJobContainers, func() { v.JobContainers = nil }},
+			{CollectorFileShareSessions, v.GetFileShareSessions, func() { v.FileShareSessions = Sessions{} }},
+		},
+	}
+
+	// the counters are kept up to date when a stage fails as well
+	defer func() {
+		v.API = v.rt.Stats()
+	}()
+
+	v.Collectors = nil
+
+	for _, collectors := range stages {
+		statuses := make([]CollectorStatus, len(collectors))
+
+		err := v.authorized(func() error {
+			return v.forEach(len(collectors), func(i int) error {
+				c := collectors[i]
+				statuses[i] = CollectorStatus{Name: c.name}
+
+				err := c.get()
+				switch {
+				case err == nil:
+				// the data is not available to this user or server version, the other collectors still run
+				case errors.Is(err, ErrPermission) || errors.Is(err, ErrNotFound):
+					v.log.Warn("Skipping collector", "collector", c.name, "error", err)
+					// the data of an earlier cycle must not be stored as if it was collected now
+					c.reset()
+					statuses[i].State, statuses[i].Err = CollectorSkipped, err
+					return nil
+				default:
+					statuses[i].State, statuses[i].Err = CollectorFailed, err
+				}
+
+				return err
+			})
+		})
+
+		v.Collectors = append(v.Collectors, statuses...)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// names of the collectors, as stored with their state
+const (
+	CollectorSessions                = "sessions"
+	CollectorManagedServers          = "managed_servers"
+	CollectorRepositories            = "repositories"
+	CollectorProxies                 = "proxies"
+	CollectorProxyStates             = "proxy_states"
+	CollectorWanAccelerators         = "wan_accelerators"
+	CollectorJobs                    = "jobs"
+	CollectorConfigBackup            = "config_backup"
+	CollectorCredentials             = "credentials"
+	CollectorCertificate             = "certificate"
+	CollectorBackupObjects           = "backup_objects"
+	CollectorUnstructuredDataServers = "unstructured_data_servers"
+	CollectorFileShareJobs           = "file_share_jobs"
+	CollectorInventory               = "inventory"
+	CollectorJobContainers           = "job_containers"
+	CollectorFileShareSessions       = "file_share_sessions"
+)
+
+// collector gets one kind of data, reset clears it when the collector is skipped
+type collector struct {
+	name  string
+	get   func() error
+	reset func()
+}
+
+type CollectorState int
+
+const (
+	CollectorOK CollectorState = iota
+	// CollectorSkipped collectors were refused by the server with a 403 or 404, their data is left empty
+	CollectorSkipped
+	CollectorFailed
+)
+
+func (s CollectorState) String() string {
+	switch s {
+	case CollectorSkipped:
+		return "skipped"
+	case CollectorFailed:
+		return "failed"
+	default:
+		return "ok"
+	}
+}
+
+// Skipped reports whether one of the collectors was skipped by the last collection, as its data is empty then
+func (v *Veeam) Skipped(names ...string) bool {
+	for _, c := range v.Collectors {
+		if c.State == CollectorSkipped && slices.Contains(names, c.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CollectorStatus is the outcome of a collector in the last collection
+type CollectorStatus struct {
+	Name  string
+	State CollectorState
+	// Err is why the collector was skipped or failed
+	Err error
+}
+
 func (v *Veeam) GetSessions() error {
 	v.log.Info("Collecting sessions information")
 
@@ -327,7 +729,11 @@ func (v *Veeam) GetSessions() error {
 		XApiVersion: v.conf.Veeam.XApiVersion,
 	})
 	if err != nil {
-		return fmt.Errorf("could not get sessions: %v", err)
+		return fmt.Errorf("could not get sessions: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get sessions: %w", err)
 	}
 
 	var ses Sessions
@@ -336,6 +742,7 @@ func (v *Veeam) GetSessions() error {
 		return fmt.Errorf("could not parse sessions: %v", err)
 	}
 
+	ses.Data = filter.Apply(v.filter, ses.Data, SessionsData.FilterObject)
 	v.Sessions = ses
 	return nil
 }
@@ -347,7 +754,11 @@ func (v *Veeam) GetManagedServers() error {
 		XApiVersion: v.conf.Veeam.XApiVersion,
 	})
 	if err != nil {
-		return fmt.Errorf("could not get managed servers: %v", err)
+		return fmt.Errorf("could not get managed servers: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get managed servers: %w", err)
 	}
 
 	var servers ManagedSevers
@@ -368,7 +779,11 @@ func (v *Veeam) GetRepositories() error {
 		XApiVersion: v.conf.Veeam.XApiVersion,
 	})
 	if err != nil {
-		return fmt.Errorf("could not get repositories: %v", err)
+		return fmt.Errorf("could not get repositories: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get repositories: %w", err)
 	}
 
 	var repos AllRepositories
@@ -376,8 +791,12 @@ func (v *Veeam) GetRepositories() error {
 		return fmt.Errorf("could not parse repositories: %v", err)
 	}
 
-	for _, r := range repos.Data {
-		uid, err := uuid.Parse(r.ID)
+	repos.Data = filter.Apply(v.filter, repos.Data, RepositoriesData.FilterObject)
+
+	// the states are requested per repository, concurrently, and kept in the order of the repositories
+	states := make([]SingleRepository, len(repos.Data))
+	if err = v.forEach(len(repos.Data), func(i int) error {
+		uid, err := uuid.Parse(repos.Data[i].ID)
 		if err != nil {
 			return fmt.Errorf("could not parse repositories uuid: %v", err)
 		}
@@ -387,19 +806,25 @@ func (v *Veeam) GetRepositories() error {
 			XApiVersion: v.conf.Veeam.XApiVersion,
 		})
 		if err != nil {
-			return fmt.Errorf("could not get repositories states: %v", err)
+			return fmt.Errorf("could not get repositories states: %w", err)
 		}
 
-		var rep SingleRepository
+		if err = checkResponse(rsr.HTTPResponse, rsr.Body); err != nil {
+			return fmt.Errorf("could not get repositories states: %w", err)
+		}
 
-		if err = json.NewDecoder(bytes.NewBuffer(rsr.Body)).Decode(&rep); err != nil {
+		if err = json.NewDecoder(bytes.NewBuffer(rsr.Body)).Decode(&states[i]); err != nil {
 			return fmt.Errorf("could not parse repositories states: %v", err)
 		}
 
-		v.AllRepositories = repos
-		v.Repositories = append(v.Repositories, rep)
+		return nil
+	}); err != nil {
+		return err
 	}
 
+	v.AllRepositories = repos
+	v.Repositories = states
+
 	return nil
 }
 
@@ -409,7 +834,11 @@ func (v *Veeam) GetProxies() error {
 		XApiVersion: v.conf.Veeam.XApiVersion,
 	})
 	if err != nil {
-		return fmt.Errorf("could not get proxies: %v", err)
+		return fmt.Errorf("could not get proxies: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get proxies: %w", err)
 	}
 
 	var pr Proxies
@@ -417,6 +846,7 @@ func (v *Veeam) GetProxies() error {
 		return fmt.Errorf("could not parse proxies: %v", err)
 	}
 
+	pr.Data = filter.Apply(v.filter, pr.Data, ProxiesData.FilterObject)
 	v.Proxies = pr
 
 	return nil
@@ -429,7 +859,11 @@ func (v *Veeam) GetBackupObjects() error {
 		XApiVersion: v.conf.Veeam.XApiVersion,
 	})
 	if err != nil {
-		return fmt.Errorf("could not get backup objects: %v", err)
+		return fmt.Errorf("could not get backup objects: %w", err)
+	}
+
+	if err = checkResponse(resp.HTTPResponse, resp.Body); err != nil {
+		return fmt.Errorf("could not get backup objects: %w", err)
 	}
 
 	var bo BackupObjects
@@ -437,6 +871,8 @@ func (v *Veeam) GetBackupObjects() error {
 		return fmt.Errorf("could not parse backup objects: %v", err)
 	}
 
+	v.allBackupObjects = bo.Data
+	bo.Data = filter.Apply(v.filter, bo.Data, BackupObjectsData.FilterObject)
 	v.BackupObjects = bo
 
 	return nil
@@ -0,0 +1,390 @@
+package veeam
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/filter"
+	"github.com/ZeljkoBenovic/govein/pkg/veeam/veeamtest"
+)
+
+func newTestVeeam(t *testing.T) (*Veeam, *veeamtest.Server) {
+	t.Helper()
+
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	v, err := NewVeeam(context.Background(), srv.Config(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	return v, srv
+}
+
+func TestNewVeeamInvalidCredentials(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.Veeam.Password = "wrong"
+
+	if _, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("expected login with invalid credentials to fail")
+	}
+}
+
+func TestSetCredentials(t *testing.T) {
+	v, srv := newTestVeeam(t)
+
+	srv.Password = "rotated"
+
+	if err := v.SetCredentials(srv.Username, "stale"); err == nil {
+		t.Fatal("expected login with the stale password to fail")
+	}
+
+	if err := v.SetCredentials(srv.Username, "rotated"); err != nil {
+		t.Fatalf("could not login with the rotated password: %v", err)
+	}
+
+	if err := v.Ping(); err != nil {
+		t.Fatalf("could not use the new token: %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	v, srv := newTestVeeam(t)
+
+	if err := v.Ping(); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+
+	if v.ServerInfo.Name != "vbr01.lab.local" {
+		t.Errorf("unexpected server name %q", v.ServerInfo.Name)
+	}
+
+	srv.InjectError("/api/v1/serverInfo", http.StatusServiceUnavailable, "ServiceUnavailable", 1)
+	if err := v.Ping(); err == nil {
+		t.Error("expected ping to fail on injected error")
+	}
+}
+
+func TestCollectors(t *testing.T) {
+	v, _ := newTestVeeam(t)
+
+	collectors := map[string]func() error{
+		"sessions":        v.GetSessions,
+		"managedServers":  v.GetManagedServers,
+		"repositories":    v.GetRepositories,
+		"proxies":         v.GetProxies,
+		"proxyStates":     v.GetProxyStates,
+		"wanAccelerators": v.GetWanAccelerators,
+		"backupObjects":   v.GetBackupObjects,
+		"unstructured":    v.GetUnstructuredDataServers,
+		"fileShareJobs":   v.GetFileShareJobs,
+		"fileShareSess":   v.GetFileShareSessions,
+		"jobs":            v.GetJobs,
+		"inventory":       v.GetInventory,
+		"jobContainers":   v.GetJobContainers,
+		"configBackup":    v.GetConfigBackup,
+		"credentials":     v.GetCredentials,
+		"certificate":     v.GetCertificate,
+	}
+
+	// collectors which depend on data gathered by others run in this order
+	order := []string{
+		"sessions", "managedServers", "repositories", "proxies", "proxyStates", "wanAccelerators",
+		"backupObjects", "unstructured", "fileShareJobs", "fileShareSess", "jobs", "inventory", "jobContainers",
+		"configBackup", "credentials", "certificate",
+	}
+
+	for _, name := range order {
+		if err := collectors[name](); err != nil {
+			t.Fatalf("%s collector failed: %v", name, err)
+		}
+	}
+
+	if got := len(v.Sessions.Data); got != 5 {
+		t.Errorf("expected 5 sessions, got %d", got)
+	}
+
+	if got := len(v.Repositories); got != 2 {
+		t.Errorf("expected 2 repository states, got %d", got)
+	}
+
+	if got := len(v.FileShareSessions.Data); got != 1 {
+		t.Errorf("expected 1 file share session, got %d", got)
+	}
+
+	if v.ConfigBackup.LastSession == nil || v.ConfigBackup.LastSession.Result.Result != "Success" {
+		t.Errorf("expected successful last configuration backup session, got %+v", v.ConfigBackup.LastSession)
+	}
+
+	if v.Certificate.ValidBy.IsZero() {
+		t.Error("expected server certificate to be collected from the tls connection")
+	}
+
+	report := v.Protection()
+	if report.Total != 3 || report.Protected != 2 || len(report.Unprotected) != 1 {
+		t.Errorf("unexpected protection report %+v", report)
+	}
+
+	if report.Unprotected[0].Name != "test-lab-07" {
+		t.Errorf("unexpected unprotected object %q", report.Unprotected[0].Name)
+	}
+}
+
+func TestCollectConcurrency(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	for _, p := range []string{
+		"/api/v1/sessions", "/api/v1/jobs", "/api/v1/backupObjects", "/api/v1/credentials",
+		"/api/v1/backupInfrastructure/proxies", "/api/v1/backupInfrastructure/repositories/states",
+	} {
+		srv.SetLatency(p, 50*time.Millisecond)
+	}
+
+	conf := srv.Config()
+	conf.Veeam.Concurrency = 3
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	// a second cycle replaces the repository states instead of appending to them
+	for range 2 {
+		if err = v.Collect(); err != nil {
+			t.Fatalf("could not collect: %v", err)
+		}
+	}
+
+	if got := len(v.Repositories); got != 2 || len(v.AllRepositories.Data) != 2 {
+		t.Errorf("expected 2 repository states, got %d", got)
+	}
+
+	// the collectors and the per-item requests of the repositories collector share the limit of 3 requests in flight
+	if peak := srv.PeakConcurrency(); peak < 2 || peak > 3 {
+		t.Errorf("unexpected number of concurrent requests %d", peak)
+	}
+
+	if report := v.Protection(); report.Total != 3 {
+		t.Errorf("expected the inventory after the managed servers, got %+v", report)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.Veeam.RequestTimeoutSeconds = 1
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	srv.SetLatency("/api/v1/jobs", 5*time.Second)
+
+	err = v.Collect()
+	if err == nil || !strings.Contains(err.Error(), "could not get jobs") {
+		t.Fatalf("expected the jobs request to time out, got %v", err)
+	}
+}
+
+func TestCollectFilters(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.Veeam.ExcludedJobTypes = map[string]struct{}{"MalwareDetection": {}}
+	conf.Filters.Include = []filter.Rule{{Field: filter.Platform, Glob: "vmware"}}
+	conf.Filters.Exclude = []filter.Rule{
+		{Field: filter.JobName, Regex: "^SQL"},
+		{Field: filter.ObjectPath, Glob: "*test-lab-*"},
+	}
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	if err = v.Collect(); err != nil {
+		t.Fatalf("could not collect: %v", err)
+	}
+
+	// the malware detection, sql and nas sessions are filtered out
+	if got := len(v.Sessions.Data); got != 2 {
+		t.Errorf("expected 2 sessions, got %d", got)
+	}
+
+	if got := len(v.Jobs.Data); got != 2 {
+		t.Errorf("expected 2 jobs, got %d", got)
+	}
+
+	if got := len(v.BackupObjects.Data); got != 2 {
+		t.Errorf("expected 2 backup objects, got %d", got)
+	}
+
+	// lab vms are not reported as unprotected
+	report := v.Protection()
+	if report.Total != 2 || report.Protected != 2 {
+		t.Errorf("unexpected protection report %+v", report)
+	}
+}
+
+func TestProtectionContainers(t *testing.T) {
+	v, srv := newTestVeeam(t)
+
+	// the datacenter holds web01, sql01 and the lab folder with test-lab-07
+	if err := srv.SetFixture("/api/v1/jobs", []byte(`{"data": [{
+		"id": "6d0e1c2a-7b3f-4c5d-8e9f-0a1b2c3d4e10", "name": "Datacenter", "type": "Backup", "isDisabled": false,
+		"virtualMachines": {
+			"includes": [{"inventoryObject": {"hostName": "vcenter.lab.local", "name": "Datacenter", "objectId": "datacenter-1", "type": "Datacenter"}}],
+			"excludes": {"vms": [{"inventoryObject": {"hostName": "vcenter.lab.local", "name": "sql01", "objectId": "vm-102", "type": "VirtualMachine"}}]}
+		}
+	}]}`)); err != nil {
+		t.Fatalf("could not set jobs fixture: %v", err)
+	}
+
+	if err := srv.SetFixture("/api/v1/backupObjects", []byte(`{"data": []}`)); err != nil {
+		t.Fatalf("could not set backup objects fixture: %v", err)
+	}
+
+	if err := v.Collect(); err != nil {
+		t.Fatalf("could not collect: %v", err)
+	}
+
+	report := v.Protection()
+	if report.Total != 3 || report.Protected != 2 || len(report.Unprotected) != 1 {
+		t.Fatalf("unexpected protection report %+v", report)
+	}
+
+	if report.Unprotected[0].Name != "sql01" {
+		t.Errorf("expected the excluded vm to be unprotected, got %q", report.Unprotected[0].Name)
+	}
+}
+
+func TestProtectionFiltered(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	if err := srv.SetFixture("/api/v1/backupObjects", []byte(`{"data": []}`)); err != nil {
+		t.Fatalf("could not set backup objects fixture: %v", err)
+	}
+
+	conf := srv.Config()
+	conf.Filters.Exclude = []filter.Rule{
+		{Field: filter.JobName, Regex: "^SQL"},
+		// matches web01 in the inventory, and its backup object in the Datacenter folder
+		{Field: filter.ObjectPath, Glob: `vcenter.lab.local\web01`},
+	}
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	if err = v.Collect(); err != nil {
+		t.Fatalf("could not collect: %v", err)
+	}
+
+	// sql01 is protected by the filtered out sql job, web01 is filtered out of the report
+	report := v.Protection()
+	if report.Total != 2 || report.Protected != 1 || len(report.Unprotected) != 1 || report.Unprotected[0].Name != "test-lab-07" {
+		t.Errorf("unexpected protection report %+v", report)
+	}
+
+	if err = srv.SetFixture("/api/v1/backupObjects", []byte(`{"data": [{"viType": "VirtualMachine", "objectId": "vm-101",
+		"path": "vcenter.lab.local\\Datacenter\\web01", "platformName": "VMware", "id": "4c5d6e7f-8a9b-4c0d-9e1f-3a4b5c6d7e01",
+		"name": "web01", "type": "VM", "platformId": "00000000-0000-0000-0000-000000000000", "restorePointsCount": 14}]}`)); err != nil {
+		t.Fatalf("could not set backup objects fixture: %v", err)
+	}
+
+	if err = v.GetBackupObjects(); err != nil {
+		t.Fatalf("could not collect backup objects: %v", err)
+	}
+
+	if got := len(v.BackupObjects.Data); got != 0 {
+		t.Errorf("expected the object path rule to filter the backup object, got %d", got)
+	}
+}
+
+func TestBackupObjectPath(t *testing.T) {
+	srv := veeamtest.New()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conf := srv.Config()
+	conf.Filters.Include = []filter.Rule{
+		// the folders of the backup object path, which the inventory path of the vm does not have
+		{Field: filter.ObjectPath, Glob: `vcenter.lab.local\Datacenter\*`},
+	}
+	conf.Filters.Exclude = []filter.Rule{
+		// the inventory path of the vm
+		{Field: filter.ObjectPath, Glob: `vcenter.lab.local\sql01`},
+	}
+
+	v, err := NewVeeam(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("could not create veeam client: %v", err)
+	}
+
+	if err = v.GetBackupObjects(); err != nil {
+		t.Fatalf("could not collect backup objects: %v", err)
+	}
+
+	if len(v.BackupObjects.Data) != 1 || v.BackupObjects.Data[0].Name != "web01" {
+		t.Errorf("expected only web01 to be kept, got %+v", v.BackupObjects.Data)
+	}
+}
+
+func TestProtectionHosts(t *testing.T) {
+	// object ids are only unique within their vcenter
+	v := &Veeam{
+		Inventory: InventoryObjects{Data: []InventoryObject{
+			{HostName: "vcenter-a.lab.local", Name: "web01", ObjectID: "vm-101"},
+			{HostName: "vcenter-b.lab.local", Name: "web02", ObjectID: "vm-101"},
+		}},
+		allBackupObjects: []BackupObjectsData{
+			{ObjectID: "vm-101", Path: `vcenter-a.lab.local\Datacenter\web01`},
+		},
+	}
+
+	report := v.Protection()
+	if report.Protected != 1 || len(report.Unprotected) != 1 || report.Unprotected[0].Name != "web02" {
+		t.Errorf("unexpected protection report %+v", report)
+	}
+}
+
+func TestBackupObjectKind(t *testing.T) {
+	tests := []struct {
+		object BackupObjectsData
+		want   ObjectKind
+	}{
+		{BackupObjectsData{Type: VM, ViType: VirtualMachine, PlatformName: VMware}, KindVirtualMachine},
+		{BackupObjectsData{Type: FileShare, PlatformName: NasBackup}, KindFileShare},
+		{BackupObjectsData{Type: ObjectStorage}, KindObjectStorage},
+		{BackupObjectsData{PlatformName: WindowsPhysical}, KindComputer},
+		{BackupObjectsData{Type: "Something"}, KindUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := tt.object.Kind(); got != tt.want {
+			t.Errorf("Kind() of %+v = %s, want %s", tt.object, got, tt.want)
+		}
+	}
+}
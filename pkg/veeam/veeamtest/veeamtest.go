@@ -0,0 +1,442 @@
+// Package veeamtest provides an in-process fake of the Veeam B&R REST API,
+// serving fixture data for unit tests and local demos.
+package veeamtest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZeljkoBenovic/govein/pkg/config"
+)
+
+//go:embed fixtures/*.json
+var fixturesFS embed.FS
+
+const (
+	DefaultUsername = "admin"
+	DefaultPassword = "password"
+	APIVersion      = "1.2-rev0"
+
+	accessToken = "veeamtest-access-token"
+)
+
+// default fixture file served for each api path
+var defaultFixtures = map[string]string{
+	"/api/v1/serverInfo":   "serverInfo.json",
+	"/api/v1/sessions":     "sessions.json",
+	"/api/v1/taskSessions": "taskSessions.json",
+	"/api/v1/jobs":         "jobs.json",
+	"/api/v1/backupInfrastructure/managedServers":      "managedServers.json",
+	"/api/v1/backupInfrastructure/repositories":        "repositories.json",
+	"/api/v1/backupInfrastructure/repositories/states": "repositoriesStates.json",
+	"/api/v1/backupInfrastructure/proxies":             "proxies.json",
+	"/api/v1/backupInfrastructure/proxies/states":      "proxiesStates.json",
+	"/api/v1/backupInfrastructure/wanAccelerators":     "wanAccelerators.json",
+	"/api/v1/backupObjects":                            "backupObjects.json",
+	"/api/v1/inventory/unstructuredDataServers":        "unstructuredDataServers.json",
+	"/api/v1/inventory/vmware/hosts/vcenter.lab.local": "inventoryVcenter.json",
+	"/api/v1/configBackup":                             "configBackup.json",
+	"/api/v1/credentials":                              "credentials.json",
+	"/api/v1/serverCertificate":                        "serverCertificate.json",
+}
+
+// filter query parameters and the fields of the returned items they match against.
+// The inventory fixtures hold the parent of each object in parentContainerName, which the real api does not return.
+var filterFields = map[string][]string{
+	"idFilter":                  {"id"},
+	"jobIdFilter":               {"jobId"},
+	"typeFilter":                {"type", "sessionType"},
+	"nameFilter":                {"name"},
+	"parentContainerNameFilter": {"parentContainerName"},
+}
+
+// Server is a fake Veeam B&R REST API server.
+// All methods are safe for concurrent use.
+type Server struct {
+	Username string
+	Password string
+
+	mu       sync.Mutex
+	fixtures map[string]json.RawMessage
+	errors   map[string]*injectedError
+	requests map[string]int
+	latency  map[string]time.Duration
+	// tokens counts the expired tokens, the issued token changes with it
+	tokens int
+	// inFlight and peak count the requests being served at once
+	inFlight int
+	peak     int
+	ts       *httptest.Server
+}
+
+type injectedError struct {
+	status int
+	code   string
+	times  int
+}
+
+// New creates a fake server loaded with the default fixtures, which is not listening yet.
+// Use Start to run it on a random local port, or use it as an http.Handler.
+func New() *Server {
+	s := &Server{
+		Username: DefaultUsername,
+		Password: DefaultPassword,
+		fixtures: make(map[string]json.RawMessage),
+		errors:   make(map[string]*injectedError),
+		requests: make(map[string]int),
+		latency:  make(map[string]time.Duration),
+	}
+
+	for p, file := range defaultFixtures {
+		b, err := fixturesFS.ReadFile(path.Join("fixtures", file))
+		if err != nil {
+			panic(fmt.Sprintf("veeamtest: could not read fixture %s: %v", file, err))
+		}
+
+		s.fixtures[p] = b
+	}
+
+	return s
+}
+
+// Start serves the fake api over tls on a random local port and returns its url
+func (s *Server) Start() string {
+	s.ts = httptest.NewTLSServer(s)
+	return s.ts.URL
+}
+
+// Listen serves the fake api over tls on the given address and returns its url
+func (s *Server) Listen(addr string) (string, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	s.ts = httptest.NewUnstartedServer(s)
+	s.ts.Listener.Close()
+	s.ts.Listener = l
+	s.ts.StartTLS()
+
+	return s.ts.URL, nil
+}
+
+// Close stops the server started with Start or Listen
+func (s *Server) Close() {
+	if s.ts != nil {
+		s.ts.Close()
+	}
+}
+
+// Config returns a config pointing to the running server
+func (s *Server) Config() config.Config {
+	return config.Config{
+		Veeam: config.Veeam{
+			Host:                s.ts.URL,
+			XApiVersion:         APIVersion,
+			TrustSelfSignedCert: true,
+			Username:            s.Username,
+			Password:            s.Password,
+		},
+	}
+}
+
+// SetFixture replaces the response body served for the api path, v is encoded as json unless it is already []byte
+func (s *Server) SetFixture(apiPath string, v any) error {
+	b, ok := v.([]byte)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures[apiPath] = b
+
+	return nil
+}
+
+// InjectError makes the next requests to the api path fail with the given status code and veeam error code.
+// A negative times fails every request until ClearErrors is called.
+func (s *Server) InjectError(apiPath string, status int, errorCode string, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors[apiPath] = &injectedError{status: status, code: errorCode, times: times}
+}
+
+// ClearErrors removes every injected error
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors = make(map[string]*injectedError)
+}
+
+// SetLatency delays the responses of the api path, a request giving up early is not answered
+func (s *Server) SetLatency(apiPath string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency[apiPath] = d
+}
+
+// ExpireTokens refuses the tokens issued so far, as the veeam server does once they expire
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens++
+}
+
+func (s *Server) accessToken() string {
+	if s.tokens == 0 {
+		return accessToken
+	}
+
+	return fmt.Sprintf("%s-%d", accessToken, s.tokens)
+}
+
+// PeakConcurrency returns the highest number of requests served at once
+func (s *Server) PeakConcurrency() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.peak
+}
+
+// Requests returns the number of requests received for the api path
+func (s *Server) Requests(apiPath string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[apiPath]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	injected := s.takeError(r.URL.Path)
+	body, found := s.fixtures[r.URL.Path]
+	latency := s.latency[r.URL.Path]
+	token := s.accessToken()
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if injected != nil {
+		writeError(w, injected.status, injected.code, fmt.Sprintf("injected error for %s", r.URL.Path))
+		return
+	}
+
+	if r.Header.Get("x-api-version") == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "x-api-version header is required")
+		return
+	}
+
+	if r.URL.Path == "/api/oauth2/token" {
+		s.token(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "authorization token is missing or invalid")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "only GET requests are supported")
+		return
+	}
+
+	if !found {
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s was not found", r.URL.Path))
+		return
+	}
+
+	body, err := query(body, r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "UnknownError", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (s *Server) takeError(apiPath string) *injectedError {
+	e, ok := s.errors[apiPath]
+	if !ok {
+		return nil
+	}
+
+	if e.times > 0 {
+		e.times--
+		if e.times == 0 {
+			delete(s.errors, apiPath)
+		}
+	}
+
+	return e
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	if r.PostForm.Get("username") != s.Username || r.PostForm.Get("password") != s.Password {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "invalid username or password")
+		return
+	}
+
+	s.mu.Lock()
+	token := s.accessToken()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  token,
+		"token_type":    "bearer",
+		"refresh_token": "veeamtest-refresh-token",
+		"expires_in":    900,
+	})
+}
+
+// query applies the filter, ordering and pagination parameters to list responses
+func query(body json.RawMessage, r *http.Request) ([]byte, error) {
+	var list struct {
+		Data []map[string]any `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &list); err != nil || list.Data == nil {
+		// not a list response
+		return body, nil
+	}
+
+	q := r.URL.Query()
+	items := make([]map[string]any, 0, len(list.Data))
+
+	for _, item := range list.Data {
+		if matches(item, q) && created(item, q) {
+			items = append(items, item)
+		}
+	}
+
+	if col := q.Get("orderColumn"); col != "" {
+		field := strings.ToLower(col[:1]) + col[1:]
+		asc := q.Get("orderAsc") != "false"
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := fmt.Sprint(items[i][field]), fmt.Sprint(items[j][field])
+			if asc {
+				return a < b
+			}
+			return a > b
+		})
+	}
+
+	total := len(items)
+	skip, _ := strconv.Atoi(q.Get("skip"))
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 200
+	}
+
+	skip = min(max(skip, 0), total)
+	end := min(skip+limit, total)
+	items = items[skip:end]
+
+	return json.Marshal(map[string]any{
+		"data": items,
+		"pagination": map[string]int{
+			"total": total,
+			"count": len(items),
+			"skip":  skip,
+			"limit": limit,
+		},
+	})
+}
+
+func matches(item map[string]any, q map[string][]string) bool {
+	for param, fields := range filterFields {
+		want, ok := q[param]
+		if !ok || len(want) == 0 {
+			continue
+		}
+
+		matched := false
+		for _, f := range fields {
+			if v, ok := item[f]; ok && strings.EqualFold(fmt.Sprint(v), want[0]) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// created applies the createdAfterFilter and createdBeforeFilter parameters to the creationTime of the item
+func created(item map[string]any, q url.Values) bool {
+	after, before := q.Get("createdAfterFilter"), q.Get("createdBeforeFilter")
+	if after == "" && before == "" {
+		return true
+	}
+
+	t, err := time.Parse(time.RFC3339, fmt.Sprint(item["creationTime"]))
+	if err != nil {
+		return false
+	}
+
+	if a, err := time.Parse(time.RFC3339, after); err == nil && t.Before(a) {
+		return false
+	}
+
+	if b, err := time.Parse(time.RFC3339, before); err == nil && !t.Before(b) {
+		return false
+	}
+
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errorCode":  code,
+		"message":    message,
+		"resourceId": nil,
+	})
+}
@@ -0,0 +1,89 @@
+package veeamtest
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func get(t *testing.T, url string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("x-api-version", APIVersion)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	cl := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := cl.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, body
+}
+
+func TestPagination(t *testing.T) {
+	srv := New()
+	url := srv.Start()
+	t.Cleanup(srv.Close)
+
+	_, body := get(t, url+"/api/v1/sessions?skip=1&limit=2")
+
+	data := body["data"].([]any)
+	if len(data) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(data))
+	}
+
+	pagination := body["pagination"].(map[string]any)
+	if pagination["total"].(float64) != 5 || pagination["skip"].(float64) != 1 {
+		t.Errorf("unexpected pagination %v", pagination)
+	}
+
+	if id := data[0].(map[string]any)["id"]; id != "b5a0cb8e-2f7e-4a45-9cbb-1a0d4f3c2e02" {
+		t.Errorf("unexpected first session %v", id)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	srv := New()
+	url := srv.Start()
+	t.Cleanup(srv.Close)
+
+	_, body := get(t, url+"/api/v1/sessions?typeFilter=ConfigurationBackup")
+
+	if data := body["data"].([]any); len(data) != 1 {
+		t.Fatalf("expected 1 configuration backup session, got %d", len(data))
+	}
+}
+
+func TestInjectError(t *testing.T) {
+	srv := New()
+	url := srv.Start()
+	t.Cleanup(srv.Close)
+
+	srv.InjectError("/api/v1/jobs", http.StatusForbidden, "Forbidden", 1)
+
+	resp, body := get(t, url+"/api/v1/jobs")
+	if resp.StatusCode != http.StatusForbidden || body["errorCode"] != "Forbidden" {
+		t.Fatalf("expected injected forbidden error, got %d %v", resp.StatusCode, body)
+	}
+
+	if resp, _ = get(t, url+"/api/v1/jobs"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected injected error to be consumed, got %d", resp.StatusCode)
+	}
+
+	if got := srv.Requests("/api/v1/jobs"); got != 2 {
+		t.Errorf("expected 2 recorded requests, got %d", got)
+	}
+}